@@ -0,0 +1,112 @@
+package kubescaler
+
+import (
+	"context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	GameServerStateReady     = "Ready"
+	GameServerStateAllocated = "Allocated"
+	GameServerStateReserved  = "Reserved"
+	GameServerStateShutdown  = "Shutdown"
+)
+
+var (
+	GameServerGVR = schema.GroupVersionResource{
+		Group:    "agones.dev",
+		Version:  "v1",
+		Resource: "gameservers",
+	}
+	FleetGVR = schema.GroupVersionResource{
+		Group:    "agones.dev",
+		Version:  "v1",
+		Resource: "fleets",
+	}
+)
+
+// Agones reads the Agones GameServer states and Fleet sizes, the GameServer pods have the same name as their GameServer
+type Agones struct {
+	dc        dynamic.Interface
+	namespace string
+}
+
+// NewAgones returns the Agones reader of the namespace (empty for all namespaces)
+func NewAgones(dc dynamic.Interface, namespace string) *Agones {
+	return &Agones{
+		dc:        dc,
+		namespace: namespace,
+	}
+}
+
+// GameServerStates returns the GameServer states keyed by namespace/name
+func (a *Agones) GameServerStates(ctx context.Context) (map[string]string, error) {
+	list, err := a.dc.Resource(GameServerGVR).Namespace(a.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]string)
+	for _, gs := range list.Items {
+		state, _, _ := unstructured.NestedString(gs.Object, "status", "state")
+		states[gs.GetNamespace()+"/"+gs.GetName()] = state
+	}
+	return states, nil
+}
+
+// FleetDeficit returns the GameServers requested by the Fleets which are not created yet
+func (a *Agones) FleetDeficit(ctx context.Context) (int64, error) {
+	list, err := a.dc.Resource(FleetGVR).Namespace(a.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	var deficit int64
+	for _, f := range list.Items {
+		desired, _, _ := unstructured.NestedInt64(f.Object, "spec", "replicas")
+		current, _, _ := unstructured.NestedInt64(f.Object, "status", "replicas")
+		if desired > current {
+			deficit += desired - current
+		}
+	}
+	return deficit, nil
+}
+
+// refreshGameServers reads the GameServer states and the Fleet deficit for the scale pass
+func (s *Scaler) refreshGameServers() error {
+	if s.config.Agones == nil {
+		s.gameServers = nil
+		s.fleetDeficit = 0
+		return nil
+	}
+
+	states, err := s.config.Agones.GameServerStates(context.Background())
+	if err != nil {
+		return err
+	}
+
+	deficit, err := s.config.Agones.FleetDeficit(context.Background())
+	if err != nil {
+		return err
+	}
+	s.gameServers = states
+	s.fleetDeficit = deficit
+	return nil
+}
+
+// gameServerSessionState maps the GameServer state of the pod to the session state, the pods without GameServer have no state
+func (s *Scaler) gameServerSessionState(namespace, name string) string {
+	switch s.gameServers[namespace+"/"+name] {
+	case GameServerStateReady:
+		return SessionStateIdle
+	case GameServerStateAllocated, GameServerStateReserved:
+		return SessionStateAllocated
+	case GameServerStateShutdown:
+		return SessionStateShuttingDown
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,163 @@
+package kubescaler
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/theredrad/kubescaler/mocks"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"testing"
+)
+
+func TestScaler_agones(t *testing.T) {
+	nodes := newNodeList(1, false)
+	pods := newPodList(repeatRequestPod(4, requestPod{cpuResource: "0.1", isDedicatedServer: true}))
+
+	var objects []runtime.Object
+	for i, state := range []string{GameServerStateReady, GameServerStateReady, GameServerStateAllocated, GameServerStateReserved} {
+		pods.Items[i].Namespace = "default"
+		objects = append(objects, newGameServer(pods.Items[i].Namespace, pods.Items[i].Name, state))
+	}
+	objects = append(objects, newFleet("default", "dedicated-servers", 6, 4))
+
+	dc := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		GameServerGVR: "GameServerList",
+		FleetGVR:      "FleetList",
+	}, objects...)
+
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): pods,
+	})
+	srv := NewScaler(nil, NewK8S(clientSet), &Config{
+		NodeSelector:  nodeSelector,
+		PodCPURequest: 100,
+		PodLabelName:  podLabelName,
+		PodLabelValue: podLabelValue,
+		Agones:        NewAgones(dc, ""),
+	})
+
+	err := srv.refreshGameServers()
+	if err != nil {
+		t.Logf("expected game servers, got err: %s", err)
+		t.FailNow()
+	}
+
+	list, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	// 6 free slots and 2 ready game servers are available, the fleet needs 2 more game servers
+	if available, used := srv.availableSlot(list), srv.UsedSlot(list); available != 8 || used != 2 || srv.fleetDeficit != 2 {
+		t.Logf("expected 8 available, 2 used slots and 2 fleet deficit, got %d available, %d used slots and %d fleet deficit", available, used, srv.fleetDeficit)
+		t.FailNow()
+	}
+}
+
+func TestScaler_scaleFleetDeficit(t *testing.T) {
+	tests := []struct {
+		name          string
+		replicas      int64
+		expectedSizes []int
+	}{
+		{
+			name:     "deficit_fits_in_buffer",
+			replicas: 4,
+		},
+		{
+			name:          "deficit_exceeds_buffer",
+			replicas:      10,
+			expectedSizes: []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newNodeList(1, false)
+			pods := newPodList(repeatRequestPod(2, requestPod{cpuResource: "0.1", isDedicatedServer: true}))
+
+			var objects []runtime.Object
+			for i := range pods.Items {
+				pods.Items[i].Namespace = "default"
+				objects = append(objects, newGameServer(pods.Items[i].Namespace, pods.Items[i].Name, GameServerStateAllocated))
+			}
+			objects = append(objects, newFleet("default", "dedicated-servers", tt.replicas, 2))
+
+			dc := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				GameServerGVR: "GameServerList",
+				FleetGVR:      "FleetList",
+			}, objects...)
+
+			clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+				fmt.Sprintf(fmtSpecNodeName, "0"): pods,
+			})
+
+			var sizes []int
+			npm := mocks.NewMockNodePoolProvider(gomock.NewController(t))
+			npm.EXPECT().ResizeNode(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, count int) error {
+				sizes = append(sizes, count)
+				return nil
+			}).AnyTimes()
+
+			// 8 free slots with a buffer of 4 slots
+			srv := NewScaler(npm, NewK8S(clientSet), &Config{
+				NodeSelector:   nodeSelector,
+				MaximumNode:    4,
+				PodCPURequest:  100,
+				PodLabelName:   podLabelName,
+				PodLabelValue:  podLabelValue,
+				BufferSlotSize: 4,
+				Agones:         NewAgones(dc, ""),
+			})
+
+			err := srv.scale()
+			if err != nil {
+				t.Logf("expected scale, got err: %s", err)
+				t.FailNow()
+			}
+
+			if fmt.Sprint(sizes) != fmt.Sprint(tt.expectedSizes) {
+				t.Logf("expected resizes %v, got %v", tt.expectedSizes, sizes)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func newGameServer(namespace, name, state string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"state": state,
+			},
+		},
+	}
+	u.SetAPIVersion("agones.dev/v1")
+	u.SetKind("GameServer")
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func newFleet(namespace, name string, replicas, current int64) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+			},
+			"status": map[string]interface{}{
+				"replicas": current,
+			},
+		},
+	}
+	u.SetAPIVersion("agones.dev/v1")
+	u.SetKind("Fleet")
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
@@ -0,0 +1,84 @@
+package kubescaler
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+type BufferMode string
+
+const (
+	// BufferModeFixed uses the BufferSlotSize as buffer
+	BufferModeFixed BufferMode = "fixed"
+	// BufferModePercent uses a percentage of the used slots as buffer
+	BufferModePercent BufferMode = "percent"
+	// BufferModeStep uses the buffer of the greatest step not exceeding the used slots
+	BufferModeStep BufferMode = "step"
+)
+
+type BufferStep struct {
+	UsedSlot       int64 `json:"usedSlot" mapstructure:"used-slot"`
+	BufferSlotSize int64 `json:"bufferSlotSize" mapstructure:"buffer-slot-size"`
+}
+
+// UsedSlot returns the count of the dedicated server pods on the nodes, the idle servers are not used
+func (s *Scaler) UsedSlot(nodes *NodeList) int64 {
+	var used int64
+	for _, n := range nodes.Nodes {
+		used += int64(len(s.activeServers(n.Pods)))
+	}
+	return used
+}
+
+func (s *Scaler) bufferSize(used int64) int64 {
+	var size int64
+	switch s.config.BufferMode {
+	case BufferModePercent:
+		size = int64(math.Ceil(float64(used) * s.config.BufferPercent / 100))
+	case BufferModeStep:
+		size = s.stepBufferSize(used)
+	default:
+		size = s.config.BufferSlotSize
+	}
+
+	if forecast, ok := s.forecastBufferSize(used); ok && forecast > size {
+		size = forecast
+	}
+
+	if s.config.MinBufferSlotSize > 0 && size < s.config.MinBufferSlotSize {
+		size = s.config.MinBufferSlotSize
+	}
+	if s.config.MaxBufferSlotSize > 0 && size > s.config.MaxBufferSlotSize {
+		size = s.config.MaxBufferSlotSize
+	}
+
+	// the schedules raise the buffer size, the computed buffer is kept if it's larger
+	if scheduled, _ := s.scheduledOverrides(); scheduled > size {
+		size = scheduled
+	}
+
+	if s.config.Reservations != nil {
+		size += s.config.Reservations.Slots(time.Now())
+	}
+
+	s.config.Logger.Debugf("used slot: %d, buffer mode: %s, buffer size: %d", used, s.config.BufferMode, size)
+	return size
+}
+
+func (s *Scaler) stepBufferSize(used int64) int64 {
+	steps := make([]BufferStep, len(s.config.BufferSteps))
+	copy(steps, s.config.BufferSteps)
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].UsedSlot < steps[j].UsedSlot
+	})
+
+	size := s.config.BufferSlotSize
+	for _, step := range steps {
+		if step.UsedSlot > used {
+			break
+		}
+		size = step.BufferSlotSize
+	}
+	return size
+}
@@ -0,0 +1,81 @@
+package kubescaler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScaler_bufferSize(t *testing.T) {
+	nodes := newNodeList(2, false)
+	list := &NodeList{
+		Nodes: []*Node{
+			{N: &nodes.Items[0], Pods: newPodList(repeatRequestPod(15, requestPod{cpuResource: "0.1", isDedicatedServer: true})).Items},
+			{N: &nodes.Items[1], Pods: newPodList(repeatRequestPod(5, requestPod{cpuResource: "0.1", isDedicatedServer: true})).Items},
+		},
+	}
+	everyMinuteSchedule := Schedule{
+		Name:           "peak",
+		Cron:           "* * * * *",
+		Duration:       time.Hour,
+		BufferSlotSize: 20,
+	}
+	steps := []BufferStep{
+		{UsedSlot: 100, BufferSlotSize: 40},
+		{UsedSlot: 10, BufferSlotSize: 8},
+	}
+
+	tests := []struct {
+		name         string
+		config       *Config
+		expectedSize int64
+	}{
+		{
+			name:         "fixed",
+			config:       &Config{BufferSlotSize: 4},
+			expectedSize: 4,
+		},
+		{
+			name:         "percent",
+			config:       &Config{BufferMode: BufferModePercent, BufferPercent: 12},
+			expectedSize: 3,
+		},
+		{
+			name:         "percent_clamped",
+			config:       &Config{BufferMode: BufferModePercent, BufferPercent: 50, MinBufferSlotSize: 2, MaxBufferSlotSize: 6},
+			expectedSize: 6,
+		},
+		{
+			name:         "step",
+			config:       &Config{BufferMode: BufferModeStep, BufferSlotSize: 2, BufferSteps: steps},
+			expectedSize: 8,
+		},
+		{
+			name:         "scheduled",
+			config:       &Config{BufferSlotSize: 4, Schedules: []Schedule{everyMinuteSchedule}},
+			expectedSize: 20,
+		},
+		{
+			name:         "scheduled_below_computed",
+			config:       &Config{BufferMode: BufferModePercent, BufferPercent: 200, Schedules: []Schedule{everyMinuteSchedule}},
+			expectedSize: 40,
+		},
+		{
+			name:         "step_default",
+			config:       &Config{BufferMode: BufferModeStep, BufferSlotSize: 2, BufferSteps: steps[:1]},
+			expectedSize: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.PodLabelName = podLabelName
+			tt.config.PodLabelValue = podLabelValue
+			srv := NewScaler(nil, nil, tt.config)
+
+			if size := srv.bufferSize(srv.UsedSlot(list)); size != tt.expectedSize {
+				t.Logf("expected buffer size %d, got %d", tt.expectedSize, size)
+				t.FailNow()
+			}
+		})
+	}
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"github.com/theredrad/kubescaler"
 	"github.com/theredrad/kubescaler/nodepoolmanager"
 	"github.com/theredrad/kubescaler/nodepoolmanager/providers/digitalocean"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
@@ -28,9 +31,60 @@ const (
 	confPodLabelName        = "server-pod-label-name"
 	confPodLabelValue       = "server-pod-label-value"
 	confSlotBufferSize      = "buffer-slot-size"
+	confBufferMode          = "buffer-mode"
+	confBufferPercent       = "buffer-percent"
+	confBufferSteps         = "buffer-steps"
+	confMinSlotBufferSize   = "min-buffer-slot-size"
+	confMaxSlotBufferSize   = "max-buffer-slot-size"
+	confSchedules           = "schedules"
+	confHistoryPath         = "history-path"
+	confHistoryRetention    = "history-retention-sec"
+	confHistoryInterval     = "history-interval-sec"
+	confForecastHorizon     = "forecast-horizon-sec"
+	confForecastSeason      = "forecast-season-sec"
 	confScaleLoopTickSec    = "scale-loop-tick-sec"
 	confServerCPUResReq     = "server-cpu-resource-request"
 	confEmptyNodeExpiration = "empty-node-expiration-sec"
+	confMaxNodeAge          = "max-node-age-sec"
+	confSessionStateKey     = "session-state-key"
+	confAgonesMode          = "agones-mode"
+	confAgonesNamespace     = "agones-namespace"
+	confZoneBufferSize      = "zone-buffer-slot-size"
+	confInterruptionTaints  = "interruption-taints"
+	confInterruptionConds   = "interruption-conditions"
+	confFallbackNodePool    = "fallback-node-pool-name"
+	confFallbackSelector    = "fallback-node-selector"
+	confFallbackMaxNode     = "fallback-maximum-node-pool-size"
+	confFallbackDuration    = "fallback-duration-sec"
+	confMaxScaleUpStep      = "max-scale-up-step"
+	confMaxUnschedulePass   = "max-unschedule-per-pass"
+	confMaxConcurrentDelete = "max-concurrent-deletions"
+	confScaleDownStrategy   = "scale-down-strategy"
+	confConsolidationScorer = "consolidation-scorer"
+	confScaleUpCooldown     = "scale-up-cooldown-sec"
+	confScaleDownStabilize  = "scale-down-stabilization-sec"
+	confMinResizeInterval   = "min-resize-interval-sec"
+	confNodeBootTimeout     = "node-boot-timeout-sec"
+	confUnhealthyNodeTime   = "unhealthy-node-timeout-sec"
+	confDrainTaintKey       = "drain-taint-key"
+	confDrainTimeout        = "drain-timeout-sec"
+	confDrainGracePeriod    = "drain-grace-period-sec"
+	confMaxDrainDuration    = "max-drain-duration-sec"
+	confSessionDeadlineWait = "session-deadline-grace-period-sec"
+	confNotifyDraining      = "notify-draining"
+	confDrainNotifyPort     = "drain-notify-port"
+	confDrainNotifyPath     = "drain-notify-path"
+	confWarmUpTaintKey      = "warm-up-taint-key"
+	confWarmUpPodSelector   = "warm-up-pod-selector"
+	confWarmUpTimeout       = "warm-up-timeout-sec"
+	confReservationAddr     = "reservation-api-addr"
+	confReservationToken    = "reservation-api-token"
+	confReservationMaxSlots = "reservation-max-slots"
+	confReservationMaxTTL   = "reservation-max-ttl-sec"
+	confConfigPath          = "config-path"
+	confControllerMode      = "controller-mode"
+	confControllerNamespace = "controller-namespace"
+	confControllerResyncSec = "controller-resync-sec"
 )
 
 func init() {
@@ -38,31 +92,70 @@ func init() {
 }
 
 func main() {
-	providerConfig, err := initCloudProviderConfig(viper.GetString(confCloudProvider))
+	k8s, err := kubescaler.NewK8SFromKubeConfig(viper.GetString(confKubeConfigMasterURL), viper.GetString(confKubeConfigPath))
 	if err != nil {
 		panic(err)
 	}
-	cloudProvider, err := nodepoolmanager.New(viper.GetString(confCloudProvider), providerConfig)
+
+	if viper.GetBool(confControllerMode) {
+		runController(k8s)
+		return
+	}
+
+	providerConfig, err := initCloudProviderConfig(viper.GetString(confCloudProvider), viper.GetString(confNodePoolName))
 	if err != nil {
 		panic(err)
 	}
-	k8s, err := kubescaler.NewK8SFromKubeConfig(viper.GetString(confKubeConfigMasterURL), viper.GetString(confKubeConfigPath))
+	cloudProvider, err := nodepoolmanager.New(viper.GetString(confCloudProvider), providerConfig)
 	if err != nil {
 		panic(err)
 	}
 
-	scaler := kubescaler.NewScaler(cloudProvider, k8s, &kubescaler.Config{
-		NodeSelector:        viper.GetString(confNodeSelector),
-		MinimumNode:         viper.GetInt(confMinNodePoolSize),
-		MaximumNode:         viper.GetInt(confMaxNodePoolSize),
-		PodCPURequest:       viper.GetInt64(confServerCPUResReq),
-		PodLabelName:        viper.GetString(confPodLabelName),
-		PodLabelValue:       viper.GetString(confPodLabelValue),
-		EmptyNodeExpiration: time.Duration(viper.GetInt(confEmptyNodeExpiration)) * time.Second,
-		BufferSlotSize:      viper.GetInt64(confSlotBufferSize),
-		ScaleLoopDuration:   time.Duration(viper.GetInt(confScaleLoopTickSec)) * time.Second,
-		Logger:              kubescaler.NewDefaultLogger(log.New(os.Stdout, "[INFO]: ", log.Ldate), log.New(os.Stdout, "[DEBUG]: ", log.Ldate), log.New(os.Stdout, "[ERROR]: ", log.Ldate)),
-	})
+	var fallbackProvider nodepoolmanager.Provider
+	if viper.GetString(confFallbackNodePool) != "" {
+		if viper.GetInt(confFallbackMaxNode) <= 0 {
+			panic(errors.New(confFallbackMaxNode + " must be set with " + confFallbackNodePool))
+		}
+		fallbackConfig, err := initCloudProviderConfig(viper.GetString(confCloudProvider), viper.GetString(confFallbackNodePool))
+		if err != nil {
+			panic(err)
+		}
+		fallbackProvider, err = nodepoolmanager.New(viper.GetString(confCloudProvider), fallbackConfig)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var reservations *kubescaler.Reservations
+	if addr := viper.GetString(confReservationAddr); addr != "" {
+		reservations = kubescaler.NewReservations(viper.GetInt64(confReservationMaxSlots), time.Duration(viper.GetInt(confReservationMaxTTL))*time.Second)
+		go serveReservations(addr, viper.GetString(confReservationToken), reservations)
+	}
+
+	// the history store and the agones client are kept across the config reloads
+	var history kubescaler.History
+	if viper.GetString(confHistoryPath) != "" {
+		history, err = kubescaler.NewFileHistory(viper.GetString(confHistoryPath), time.Duration(viper.GetInt(confHistoryRetention))*time.Second)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var agones *kubescaler.Agones
+	if viper.GetBool(confAgonesMode) {
+		dc, err := kubescaler.DynamicClient(viper.GetString(confKubeConfigMasterURL), viper.GetString(confKubeConfigPath))
+		if err != nil {
+			panic(err)
+		}
+		agones = kubescaler.NewAgones(dc, viper.GetString(confAgonesNamespace))
+	}
+
+	config := scalerConfig()
+	config.FallbackProvider = fallbackProvider
+	config.Reservations = reservations
+	config.History = history
+	config.Agones = agones
+	scaler := kubescaler.NewScaler(cloudProvider, k8s, config)
 
 	err = scaler.Start()
 	if err != nil {
@@ -71,6 +164,175 @@ func main() {
 	defer scaler.Stop()
 	log.Printf("[INFO] scaler server started")
 
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		log.Printf("[INFO] config file changed: %s", e.Name)
+		config := scalerConfig()
+		config.FallbackProvider = fallbackProvider
+		config.Reservations = reservations
+		config.History = history
+		config.Agones = agones
+		scaler.UpdateConfig(config)
+	})
+	viper.WatchConfig()
+
+	waitForSignal()
+}
+
+func scalerConfig() *kubescaler.Config {
+	var bufferSteps []kubescaler.BufferStep
+	err := viper.UnmarshalKey(confBufferSteps, &bufferSteps)
+	if err != nil {
+		log.Printf("[WARN] invalid buffer steps: %s", err)
+	}
+
+	var schedules []kubescaler.Schedule
+	err = viper.UnmarshalKey(confSchedules, &schedules)
+	if err != nil {
+		log.Printf("[WARN] invalid schedules: %s", err)
+	}
+
+	interval := time.Duration(viper.GetInt(confHistoryInterval)) * time.Second
+	forecaster := &kubescaler.SeasonalForecaster{
+		Season:    time.Duration(viper.GetInt(confForecastSeason)) * time.Second,
+		Tolerance: 2 * interval,
+	}
+
+	strategy, ok := kubescaler.ScaleDownStrategies[viper.GetString(confScaleDownStrategy)]
+	if !ok {
+		log.Printf("[WARN] invalid scale down strategy: %s", viper.GetString(confScaleDownStrategy))
+	}
+
+	scorer, ok := kubescaler.NodeScorers[viper.GetString(confConsolidationScorer)]
+	if !ok && viper.GetString(confConsolidationScorer) != "" {
+		log.Printf("[WARN] invalid consolidation scorer: %s", viper.GetString(confConsolidationScorer))
+	}
+
+	return &kubescaler.Config{
+		NodeSelector:               viper.GetString(confNodeSelector),
+		MinimumNode:                viper.GetInt(confMinNodePoolSize),
+		MaximumNode:                viper.GetInt(confMaxNodePoolSize),
+		PodCPURequest:              viper.GetInt64(confServerCPUResReq),
+		PodLabelName:               viper.GetString(confPodLabelName),
+		PodLabelValue:              viper.GetString(confPodLabelValue),
+		EmptyNodeExpiration:        time.Duration(viper.GetInt(confEmptyNodeExpiration)) * time.Second,
+		BufferSlotSize:             viper.GetInt64(confSlotBufferSize),
+		BufferMode:                 kubescaler.BufferMode(viper.GetString(confBufferMode)),
+		BufferPercent:              viper.GetFloat64(confBufferPercent),
+		BufferSteps:                bufferSteps,
+		MinBufferSlotSize:          viper.GetInt64(confMinSlotBufferSize),
+		MaxBufferSlotSize:          viper.GetInt64(confMaxSlotBufferSize),
+		Schedules:                  schedules,
+		HistoryInterval:            interval,
+		Forecaster:                 forecaster,
+		ForecastHorizon:            time.Duration(viper.GetInt(confForecastHorizon)) * time.Second,
+		ScaleLoopDuration:          time.Duration(viper.GetInt(confScaleLoopTickSec)) * time.Second,
+		SessionStateKey:            viper.GetString(confSessionStateKey),
+		MaxNodeAge:                 time.Duration(viper.GetInt(confMaxNodeAge)) * time.Second,
+		ZoneBufferSlotSize:         viper.GetInt64(confZoneBufferSize),
+		MaxScaleUpStep:             viper.GetInt(confMaxScaleUpStep),
+		MaxUnschedulePerPass:       viper.GetInt(confMaxUnschedulePass),
+		MaxConcurrentDeletions:     viper.GetInt(confMaxConcurrentDelete),
+		ScaleDownStrategy:          strategy,
+		ConsolidationScorer:        scorer,
+		ScaleUpCooldown:            time.Duration(viper.GetInt(confScaleUpCooldown)) * time.Second,
+		ScaleDownStabilization:     time.Duration(viper.GetInt(confScaleDownStabilize)) * time.Second,
+		MinResizeInterval:          time.Duration(viper.GetInt(confMinResizeInterval)) * time.Second,
+		NodeBootTimeout:            time.Duration(viper.GetInt(confNodeBootTimeout)) * time.Second,
+		UnhealthyNodeTimeout:       time.Duration(viper.GetInt(confUnhealthyNodeTime)) * time.Second,
+		InterruptionTaints:         viper.GetStringSlice(confInterruptionTaints),
+		InterruptionConditions:     viper.GetStringSlice(confInterruptionConds),
+		FallbackNodeSelector:       viper.GetString(confFallbackSelector),
+		FallbackMaximumNode:        viper.GetInt(confFallbackMaxNode),
+		FallbackDuration:           time.Duration(viper.GetInt(confFallbackDuration)) * time.Second,
+		DrainTaintKey:              viper.GetString(confDrainTaintKey),
+		DrainTimeout:               time.Duration(viper.GetInt(confDrainTimeout)) * time.Second,
+		DrainGracePeriod:           time.Duration(viper.GetInt(confDrainGracePeriod)) * time.Second,
+		MaxDrainDuration:           time.Duration(viper.GetInt(confMaxDrainDuration)) * time.Second,
+		SessionDeadlineGracePeriod: time.Duration(viper.GetInt(confSessionDeadlineWait)) * time.Second,
+		NotifyDraining:             viper.GetBool(confNotifyDraining),
+		DrainNotifyPort:            viper.GetInt(confDrainNotifyPort),
+		DrainNotifyPath:            viper.GetString(confDrainNotifyPath),
+		WarmUpTaintKey:             viper.GetString(confWarmUpTaintKey),
+		WarmUpPodSelector:          viper.GetString(confWarmUpPodSelector),
+		WarmUpTimeout:              time.Duration(viper.GetInt(confWarmUpTimeout)) * time.Second,
+		Logger:                     newLogger(),
+	}
+}
+
+func runController(k8s *kubescaler.K8S) {
+	dc, err := kubescaler.DynamicClient(viper.GetString(confKubeConfigMasterURL), viper.GetString(confKubeConfigPath))
+	if err != nil {
+		panic(err)
+	}
+
+	controller := kubescaler.NewController(dc, k8s, policyProviderFactory(k8s), &kubescaler.ControllerConfig{
+		Namespace:      viper.GetString(confControllerNamespace),
+		ResyncDuration: time.Duration(viper.GetInt(confControllerResyncSec)) * time.Second,
+		Logger:         newLogger(),
+	})
+
+	err = controller.Start()
+	if err != nil {
+		panic(err)
+	}
+	defer controller.Stop()
+	log.Printf("[INFO] scaler controller started")
+
+	waitForSignal()
+}
+
+func policyProviderFactory(k8s *kubescaler.K8S) kubescaler.ProviderFactory {
+	return func(ctx context.Context, policy *kubescaler.ScalingPolicy) (nodepoolmanager.Provider, error) {
+		ref := policy.Spec.Provider
+		token := viper.GetString(confCloudProviderToken)
+		if ref.TokenSecretRef != nil {
+			namespace := ref.TokenSecretRef.Namespace
+			if namespace == "" {
+				namespace = policy.Namespace
+			}
+
+			var err error
+			token, err = k8s.SecretValue(ctx, namespace, ref.TokenSecretRef.Name, ref.TokenSecretRef.Key)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch ref.Name {
+		case digitalocean.DriverName:
+			return nodepoolmanager.New(ref.Name, &digitalocean.Config{
+				Token:        token,
+				ClusterName:  ref.ClusterName,
+				NodePoolName: ref.NodePoolName,
+			})
+		default:
+			return nil, errors.New("invalid cloud provider driver")
+		}
+	}
+}
+
+func serveReservations(addr, token string, reservations *kubescaler.Reservations) {
+	if token == "" {
+		log.Printf("[WARN] reservation api has no token, bind it to a private address")
+	}
+
+	handler := kubescaler.NewReservationHandler(reservations, token)
+	mux := http.NewServeMux()
+	mux.Handle("/reservations", handler)
+	mux.Handle("/reservations/", handler)
+
+	log.Printf("[INFO] reservation api listening on %s", addr)
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		log.Printf("[ERROR] reservation api stopped: %s", err)
+	}
+}
+
+func newLogger() kubescaler.Logger {
+	return kubescaler.NewDefaultLogger(log.New(os.Stdout, "[INFO]: ", log.Ldate), log.New(os.Stdout, "[DEBUG]: ", log.Ldate), log.New(os.Stdout, "[ERROR]: ", log.Ldate))
+}
+
+func waitForSignal() {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, os.Interrupt)
 	select {
@@ -95,9 +357,58 @@ func initConfig() {
 	flags.String(confPodLabelName, "", "maximum node pool size")
 	flags.String(confPodLabelValue, "", "maximum node pool size")
 	flags.Int64(confSlotBufferSize, 4, "buffer slot size")
+	flags.String(confBufferMode, string(kubescaler.BufferModeFixed), "buffer mode (fixed, percent or step)")
+	flags.Float64(confBufferPercent, 0, "buffer size in percent of the used slots in percent mode")
+	flags.Int64(confMinSlotBufferSize, 0, "minimum buffer slot size (0 is unlimited)")
+	flags.Int64(confMaxSlotBufferSize, 0, "maximum buffer slot size (0 is unlimited)")
 	flags.Int64(confScaleLoopTickSec, 10, "scale loop tick duration in sec")
 	flags.String(confServerCPUResReq, "1m", "server cpu resource request in milli unit")
 	flags.Int64(confEmptyNodeExpiration, 120, "empty node expiration time in sec")
+	flags.String(confHistoryPath, "", "used slot history file path (leave empty to disable forecasting)")
+	flags.Int64(confHistoryRetention, 8*24*60*60, "used slot history retention in sec")
+	flags.Int64(confHistoryInterval, 60, "used slot history sampling interval in sec")
+	flags.Int64(confForecastHorizon, 0, "forecast the used slots this duration in sec ahead (0 disables forecasting)")
+	flags.Int64(confForecastSeason, 7*24*60*60, "forecast season in sec, the used slots one season ago plus the trend are used as forecast")
+	flags.String(confSessionStateKey, "", "dedicated server pod annotation or label holding the session state: idle, allocated or shutting-down (leave empty to disable)")
+	flags.Bool(confAgonesMode, false, "read the session states from the Agones GameServers and deduct the Fleet replicas not created yet from the available slots")
+	flags.String(confAgonesNamespace, "", "namespace of the Agones GameServers and Fleets (leave empty for all namespaces)")
+	flags.Int64(confMaxNodeAge, 0, "recycle the nodes older than this duration in sec, a node at a time (0 disables)")
+	flags.Int64(confZoneBufferSize, 0, "minimum available slots of each zone (0 disables)")
+	flags.Int64(confMaxScaleUpStep, 0, "maximum nodes added per scale up (0 is unlimited)")
+	flags.Int64(confMaxUnschedulePass, 0, "maximum nodes marked as unschedulable per scale loop (0 is unlimited)")
+	flags.Int64(confMaxConcurrentDelete, 0, "maximum node deletions in flight (0 is unlimited)")
+	flags.String(confScaleDownStrategy, "fewest-servers", "order of the nodes to mark as unschedulable: fewest-servers, oldest, newest, session-time, cost or zone-balance")
+	flags.String(confConsolidationScorer, "", "consolidate the lightly loaded nodes scored by utilization or servers (leave empty to disable)")
+	flags.Int64(confScaleUpCooldown, 0, "duration in sec to skip marking nodes as unschedulable after a scale up")
+	flags.Int64(confScaleDownStabilize, 0, "duration in sec the extra capacity must persist before marking nodes as unschedulable")
+	flags.Int64(confMinResizeInterval, 0, "minimum duration in sec between two node pool resizes")
+	flags.Int64(confNodeBootTimeout, 0, "duration in sec to replace the nodes which are not ready after creation (0 disables)")
+	flags.Int64(confUnhealthyNodeTime, 0, "duration in sec to replace the empty nodes which are not ready (0 disables)")
+	flags.StringSlice(confInterruptionTaints, []string{"cloud.google.com/impending-node-termination", "aws-node-termination-handler/spot-itn"}, "taint keys signaling the node termination, the interrupted nodes are counted as lost capacity")
+	flags.StringSlice(confInterruptionConds, nil, "node condition types signaling the node termination when true")
+	flags.String(confFallbackNodePool, "", "fallback (e.g. on-demand) node pool name of the same cluster, scaled up when the node pool can't provide nodes (leave empty to disable)")
+	flags.String(confFallbackSelector, "", "node selector label of the fallback node pool nodes (ex: pool=on-demand)")
+	flags.Int64(confFallbackMaxNode, 0, "maximum fallback node pool size")
+	flags.Int64(confFallbackDuration, 600, "duration in sec to scale up the fallback node pool after the node pool failed to provide nodes")
+	flags.String(confDrainTaintKey, "", "NoSchedule taint key to mark nodes as unschedulable instead of cordoning (ex: kubescaler/draining)")
+	flags.Int64(confDrainTimeout, 0, "timeout in sec to evict the non dedicated server pods before deleting a node, the node is skipped for the same duration if not drained (0 disables draining)")
+	flags.Int64(confDrainGracePeriod, 0, "pod termination grace period in sec on drain eviction (0 uses the pod grace period)")
+	flags.Int64(confMaxDrainDuration, 0, "deadline in sec of the dedicated servers on the unschedulable nodes (0 disables)")
+	flags.Int64(confSessionDeadlineWait, 300, "duration in sec between signaling the session deadline to a dedicated server and deleting it")
+	flags.Bool(confNotifyDraining, false, "annotate the dedicated server pods with kubescaler/node-draining while their node is marked as unschedulable")
+	flags.Int(confDrainNotifyPort, 0, "dedicated server pods port receiving the node draining state (0 disables)")
+	flags.String(confDrainNotifyPath, "/drain", "dedicated server pods path receiving the node draining state")
+	flags.String(confWarmUpTaintKey, "", "taint key set on the new nodes by the node pool until the images are pulled (ex: kubescaler/warming-up)")
+	flags.String(confWarmUpPodSelector, "", "label selector of the image pre-pull pods, the warm-up taint is removed when the pod is ready on the node (ex: app=image-prepull)")
+	flags.Int64(confWarmUpTimeout, 600, "timeout in sec to remove the warm-up taint if the pre-pull pod isn't ready (0 waits for the pod)")
+	flags.String(confReservationAddr, "", "listen address of the reservation api (ex: :8080), leave empty to disable")
+	flags.String(confReservationToken, "", "bearer token required by the reservation api (leave empty to disable authentication)")
+	flags.Int64(confReservationMaxSlots, 1000, "maximum total reserved slots (0 is unlimited)")
+	flags.Int64(confReservationMaxTTL, 3600, "maximum reservation ttl in sec (0 is unlimited)")
+	flags.String(confConfigPath, ".", "config file directory, the config file is reloaded on change")
+	flags.Bool(confControllerMode, false, "reconcile a scaler per ScalingPolicy resource instead of using the flags")
+	flags.String(confControllerNamespace, "", "namespace to watch for ScalingPolicy resources (leave empty for all namespaces)")
+	flags.Int64(confControllerResyncSec, 10, "scaling policy resync duration in sec")
 
 	err := flags.Parse(os.Args[1:])
 	if err != nil {
@@ -109,7 +420,7 @@ func initConfig() {
 		panic(err)
 	}
 
-	viper.AddConfigPath(".")
+	viper.AddConfigPath(viper.GetString(confConfigPath))
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	err = viper.ReadInConfig()
@@ -120,13 +431,13 @@ func initConfig() {
 	viper.AutomaticEnv()
 }
 
-func initCloudProviderConfig(driver string) (interface{}, error) {
+func initCloudProviderConfig(driver, nodePoolName string) (interface{}, error) {
 	switch driver {
 	case digitalocean.DriverName:
 		return &digitalocean.Config{
 			Token:        viper.GetString(confCloudProviderToken),
 			ClusterName:  viper.GetString(confClusterName),
-			NodePoolName: viper.GetString(confNodePoolName),
+			NodePoolName: nodePoolName,
 		}, nil
 	default:
 		return nil, errors.New("invalid cloud provider driver")
@@ -0,0 +1,94 @@
+package kubescaler
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"sort"
+)
+
+// NodeScorer scores a node for consolidation by its dedicated server pods, the nodes with lower scores are consolidated first
+type NodeScorer func(n *Node, servers []v1.Pod) float64
+
+// NodeScorers are the built-in consolidation scorers by name
+var NodeScorers = map[string]NodeScorer{
+	"utilization": UtilizationScore,
+	"servers":     ServerCountScore,
+}
+
+// UtilizationScore scores the nodes by the used CPU ratio
+func UtilizationScore(n *Node, servers []v1.Pod) float64 {
+	capacity := n.ResourceCapacity(v1.ResourceCPU)
+	if capacity == 0 {
+		return 0
+	}
+	return float64(n.UsingResources(v1.ResourceCPU)) / float64(capacity)
+}
+
+// ServerCountScore scores the nodes by the dedicated server pods count
+func ServerCountScore(n *Node, servers []v1.Pod) float64 {
+	return float64(len(servers))
+}
+
+// consolidate marks the lightly loaded nodes as unschedulable if their dedicated servers fit in the slots left on the other
+// nodes in addition to the buffer, so the new servers are packed on the fuller nodes and the consolidated nodes are deleted
+// once their servers are finished. At most limit nodes are consolidated (zero is unlimited)
+func (s *Scaler) consolidate(nodes *NodeList, bufferSize int64, limit int) error {
+	if s.config.ConsolidationScorer == nil {
+		return nil
+	}
+
+	need := Resource{
+		Name:  v1.ResourceCPU,
+		Value: s.config.PodCPURequest,
+	}
+
+	type candidate struct {
+		node    *Node
+		servers int64
+		slot    int64
+		score   float64
+	}
+
+	var candidates []candidate
+	for _, n := range s.unschedulingCandidates(nodes.AvailableNodes()) {
+		servers := s.activeServers(n.Pods)
+		if len(servers) == 0 {
+			// the empty nodes are left to the unscheduling
+			continue
+		}
+		candidates = append(candidates, candidate{
+			node:    n,
+			servers: int64(len(servers)),
+			slot:    s.availableSlot(&NodeList{Nodes: []*Node{n}}),
+			score:   s.config.ConsolidationScorer(n, servers),
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score < candidates[j].score
+	})
+
+	available := s.availableSlot(nodes) - s.fleetDeficit
+	zoneSlots := nodes.ZoneAvailableSlot(need)
+	var consolidated int
+	for _, c := range candidates {
+		if limit > 0 && consolidated >= limit {
+			s.config.Logger.Debugf("consolidation limited to %d node(s) in this pass", limit)
+			break
+		}
+
+		// the node slots are lost and its servers must fit in the other nodes
+		remaining := available - c.slot - c.servers
+		if remaining < bufferSize || !s.keepsZoneBuffer(zoneSlots, c.node) {
+			continue
+		}
+
+		err := s.markNodeAsUnschedulable(c.node)
+		if err != nil {
+			return err
+		}
+		s.config.Logger.Infof("node %s with %d server(s) marked as unschedulable for consolidation, score: %f", c.node.N.Name, c.servers, c.score)
+		available = remaining
+		consolidated++
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package kubescaler
+
+import (
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestScaler_consolidate(t *testing.T) {
+	tests := []struct {
+		name                  string
+		scorer                NodeScorer
+		bufferSize            int64
+		expectedUnschedulable []string
+	}{
+		{
+			name:                  "servers",
+			scorer:                ServerCountScore,
+			bufferSize:            4,
+			expectedUnschedulable: []string{fmt.Sprintf(fmtNodeName, "2")},
+		},
+		{
+			name:                  "utilization",
+			scorer:                UtilizationScore,
+			bufferSize:            4,
+			expectedUnschedulable: []string{fmt.Sprintf(fmtNodeName, "2")},
+		},
+		{
+			name:       "large_buffer",
+			scorer:     ServerCountScore,
+			bufferSize: 13,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newNodeList(4, false)
+			clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+				fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+				fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+				fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(1, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+				fmt.Sprintf(fmtSpecNodeName, "3"): newPodList(repeatRequestPod(2, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+			})
+			srv := NewScaler(nil, NewK8S(clientSet), &Config{
+				NodeSelector:        nodeSelector,
+				PodCPURequest:       100,
+				PodLabelName:        podLabelName,
+				PodLabelValue:       podLabelValue,
+				ConsolidationScorer: tt.scorer,
+			})
+
+			list, err := srv.nodes()
+			if err != nil {
+				t.Logf("expected node list, got err: %s", err)
+				t.FailNow()
+			}
+
+			err = srv.consolidate(list, tt.bufferSize, 0)
+			if err != nil {
+				t.Logf("expected consolidation, got err: %s", err)
+				t.FailNow()
+			}
+
+			list, err = srv.nodes()
+			if err != nil {
+				t.Logf("expected node list, got err: %s", err)
+				t.FailNow()
+			}
+
+			var unschedulable []string
+			for _, n := range list.UnschedulableNodes() {
+				unschedulable = append(unschedulable, n.N.Name)
+			}
+
+			if fmt.Sprint(unschedulable) != fmt.Sprint(tt.expectedUnschedulable) {
+				t.Logf("expected unschedulable nodes %v, got %v", tt.expectedUnschedulable, unschedulable)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestScaler_scaleConsolidationLimit(t *testing.T) {
+	nodes := newNodeList(4, false)
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(1, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	srv := NewScaler(newNodePoolManagerMock(t, clientSet, nodes, false), NewK8S(clientSet), &Config{
+		NodeSelector:         nodeSelector,
+		MaximumNode:          4,
+		PodCPURequest:        100,
+		PodLabelName:         podLabelName,
+		PodLabelValue:        podLabelValue,
+		BufferSlotSize:       4,
+		MaxUnschedulePerPass: 1,
+		ConsolidationScorer:  ServerCountScore,
+	})
+
+	err := srv.scale()
+	if err != nil {
+		t.Logf("expected scale, got err: %s", err)
+		t.FailNow()
+	}
+
+	// the empty node uses the unscheduling limit of the pass, so the lightly loaded node is not consolidated
+	for _, n := range nodes.Items {
+		if n.Name == fmt.Sprintf(fmtNodeName, "2") && n.Spec.Unschedulable {
+			t.Logf("expected node %s not to be consolidated over the limit", n.Name)
+			t.FailNow()
+		}
+	}
+
+	if len(nodes.Items) != 3 {
+		t.Logf("expected the empty node to be deleted, got %d nodes", len(nodes.Items))
+		t.FailNow()
+	}
+}
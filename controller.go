@@ -0,0 +1,214 @@
+package kubescaler
+
+import (
+	"context"
+	"github.com/theredrad/kubescaler/nodepoolmanager"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"time"
+)
+
+// ProviderFactory connects to the node pool referenced by a policy
+type ProviderFactory func(ctx context.Context, policy *ScalingPolicy) (nodepoolmanager.Provider, error)
+
+type ControllerConfig struct {
+	Namespace string
+
+	ResyncDuration time.Duration
+
+	Logger Logger
+}
+
+// Controller reconciles one Scaler per ScalingPolicy object and writes the observed state into the policy status
+type Controller struct {
+	config    *ControllerConfig
+	dc        dynamic.Interface
+	k8s       Kubernetes
+	providers ProviderFactory
+
+	scalers map[types.UID]*policyScaler
+
+	stop chan bool
+}
+
+type policyScaler struct {
+	scaler       *Scaler
+	generation   int64
+	provider     ProviderReference
+	fallback     nodepoolmanager.Provider
+	fallbackPool string
+}
+
+func NewController(dc dynamic.Interface, k8s Kubernetes, providers ProviderFactory, config *ControllerConfig) *Controller {
+	if config == nil {
+		config = &ControllerConfig{}
+	}
+
+	if config.Logger == nil {
+		config.Logger = NewDefaultLogger(nil, nil, nil)
+	}
+
+	if config.ResyncDuration <= 0 {
+		config.ResyncDuration = defaultScaleLoopTickSeconds * time.Second
+	}
+
+	return &Controller{
+		config:    config,
+		dc:        dc,
+		k8s:       k8s,
+		providers: providers,
+		scalers:   make(map[types.UID]*policyScaler),
+		stop:      make(chan bool, 1),
+	}
+}
+
+func (c *Controller) Start() error {
+	err := c.reconcile()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.config.ResyncDuration)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.reconcile(); err != nil {
+					c.config.Logger.Errorf("error while reconciling scaling policies: %s", err)
+				}
+			case <-c.stop:
+				for uid := range c.scalers {
+					c.stopScaler(uid)
+				}
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *Controller) Stop() {
+	c.stop <- true
+}
+
+func (c *Controller) reconcile() error {
+	list, err := c.dc.Resource(ScalingPolicyGVR).Namespace(c.config.Namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[types.UID]bool)
+	for i := range list.Items {
+		u := &list.Items[i]
+		seen[u.GetUID()] = true
+
+		var policy ScalingPolicy
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &policy)
+		if err != nil {
+			c.config.Logger.Errorf("invalid scaling policy %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+			continue
+		}
+
+		status, err := c.reconcilePolicy(&policy)
+		if err != nil {
+			c.config.Logger.Errorf("error while reconciling scaling policy %s/%s: %s", policy.Namespace, policy.Name, err)
+			status = Status{LastError: err}
+		}
+
+		err = c.updateStatus(u, status.policyStatus(policy.Generation))
+		if err != nil {
+			c.config.Logger.Errorf("error while updating scaling policy %s/%s status: %s", policy.Namespace, policy.Name, err)
+		}
+	}
+
+	for uid := range c.scalers {
+		if !seen[uid] {
+			c.stopScaler(uid)
+		}
+	}
+	return nil
+}
+
+func (c *Controller) reconcilePolicy(policy *ScalingPolicy) (Status, error) {
+	if ps, ok := c.scalers[policy.UID]; ok {
+		if ps.generation == policy.Generation {
+			return ps.scaler.Status(), nil
+		}
+
+		if ps.provider.equal(policy.Spec.Provider) && ps.fallbackPool == policy.fallbackPool() {
+			c.config.Logger.Infof("scaling policy %s/%s changed, reloading scaler config", policy.Namespace, policy.Name)
+			ps.scaler.UpdateConfig(c.scalerConfig(policy, ps.fallback))
+			ps.generation = policy.Generation
+			return ps.scaler.Status(), nil
+		}
+
+		c.config.Logger.Infof("scaling policy %s/%s provider changed, restarting scaler", policy.Namespace, policy.Name)
+		c.stopScaler(policy.UID)
+	}
+
+	npm, err := c.providers(context.Background(), policy)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var fallback nodepoolmanager.Provider
+	if fp := policy.fallbackPolicy(); fp != nil {
+		fallback, err = c.providers(context.Background(), fp)
+		if err != nil {
+			return Status{}, err
+		}
+	}
+
+	config := c.scalerConfig(policy, fallback)
+	scaler := NewScaler(npm, c.k8s, config)
+	err = scaler.Start()
+	if err != nil {
+		return Status{}, err
+	}
+
+	c.config.Logger.Infof("scaler started for scaling policy %s/%s", policy.Namespace, policy.Name)
+	c.scalers[policy.UID] = &policyScaler{
+		scaler:       scaler,
+		generation:   policy.Generation,
+		provider:     policy.Spec.Provider,
+		fallback:     config.FallbackProvider,
+		fallbackPool: policy.fallbackPool(),
+	}
+	return scaler.Status(), nil
+}
+
+// scalerConfig returns the policy scaler config with the connected fallback provider
+func (c *Controller) scalerConfig(policy *ScalingPolicy, fallback nodepoolmanager.Provider) *Config {
+	config := policy.Config(c.config.Logger)
+	config.FallbackProvider = fallback
+	if policy.Spec.Agones != nil {
+		config.Agones = NewAgones(c.dc, policy.Spec.Agones.Namespace)
+	}
+	return config
+}
+
+func (c *Controller) stopScaler(uid types.UID) {
+	c.scalers[uid].scaler.Stop()
+	delete(c.scalers, uid)
+}
+
+func (c *Controller) updateStatus(u *unstructured.Unstructured, status ScalingPolicyStatus) error {
+	s, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return err
+	}
+
+	err = unstructured.SetNestedField(u.Object, s, "status")
+	if err != nil {
+		return err
+	}
+
+	_, err = c.dc.Resource(ScalingPolicyGVR).Namespace(u.GetNamespace()).UpdateStatus(context.Background(), u, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,111 @@
+package kubescaler
+
+import (
+	"context"
+	"github.com/theredrad/kubescaler/nodepoolmanager"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func TestController_reconcile(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	policy := newScalingPolicy("dedicated-servers", 1)
+	dc := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ScalingPolicyGVR: "ScalingPolicyList",
+	}, policy)
+
+	var connected int
+	c := NewController(dc, NewK8S(clientSet), func(ctx context.Context, p *ScalingPolicy) (nodepoolmanager.Provider, error) {
+		connected++
+		return newNodePoolManagerMock(t, clientSet, &v1.NodeList{}, false), nil
+	}, nil)
+
+	err := c.reconcile()
+	if err != nil {
+		t.Logf("expected reconcile, got err: %s", err)
+		t.FailNow()
+	}
+
+	if len(c.scalers) != 1 || connected != 1 {
+		t.Logf("expected 1 scaler, got %d scalers with %d provider connections", len(c.scalers), connected)
+		t.FailNow()
+	}
+
+	u, err := dc.Resource(ScalingPolicyGVR).Namespace(policy.GetNamespace()).Get(context.Background(), policy.GetName(), metav1.GetOptions{})
+	if err != nil {
+		t.Logf("expected scaling policy, got err: %s", err)
+		t.FailNow()
+	}
+
+	generation, _, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+	if generation != 1 {
+		t.Logf("expected observed generation 1, got %d", generation)
+		t.FailNow()
+	}
+
+	// an unchanged policy must keep the running scaler
+	err = c.reconcile()
+	if err != nil {
+		t.Logf("expected reconcile, got err: %s", err)
+		t.FailNow()
+	}
+
+	if connected != 1 {
+		t.Logf("expected running scaler to be kept, got %d provider connections", connected)
+		t.FailNow()
+	}
+
+	err = dc.Resource(ScalingPolicyGVR).Namespace(policy.GetNamespace()).Delete(context.Background(), policy.GetName(), metav1.DeleteOptions{})
+	if err != nil {
+		t.Logf("expected deleting scaling policy, got err: %s", err)
+		t.FailNow()
+	}
+
+	err = c.reconcile()
+	if err != nil {
+		t.Logf("expected reconcile, got err: %s", err)
+		t.FailNow()
+	}
+
+	if len(c.scalers) != 0 {
+		t.Logf("expected scaler to be stopped, got %d scalers", len(c.scalers))
+		t.FailNow()
+	}
+}
+
+func newScalingPolicy(name string, generation int64) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"nodeSelector": nodeSelector,
+				"podSelector": map[string]interface{}{
+					"labelName":  podLabelName,
+					"labelValue": podLabelValue,
+				},
+				"slot": map[string]interface{}{
+					"cpuRequest": int64(100),
+				},
+				"provider": map[string]interface{}{
+					"name": "mock",
+				},
+				"minimumNode":    int64(2),
+				"maximumNode":    int64(6),
+				"bufferSlotSize": int64(4),
+			},
+		},
+	}
+	u.SetAPIVersion(ScalingPolicyGroup + "/" + ScalingPolicyVersion)
+	u.SetKind(ScalingPolicyKind)
+	u.SetNamespace("default")
+	u.SetName(name)
+	u.SetUID(types.UID(name))
+	u.SetGeneration(generation)
+	return u
+}
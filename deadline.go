@@ -0,0 +1,68 @@
+package kubescaler
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"time"
+)
+
+const (
+	// sessionDeadlineAnnotation is set on the dedicated server pods remaining on a node past MaxDrainDuration, the value is
+	// the RFC3339 time the pod is deleted at
+	sessionDeadlineAnnotation = "kubescaler/session-deadline"
+)
+
+// enforceSessionDeadline signals the dedicated servers remaining on the nodes marked as unschedulable for longer than
+// MaxDrainDuration, then deletes them gracefully once the deadline is passed, so the nodes can be deleted
+func (s *Scaler) enforceSessionDeadline(nodes *NodeList) error {
+	if s.config.MaxDrainDuration <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, n := range nodes.UnschedulableNodes() {
+		if n.IsScaleDownDisabled() || n.IsProtected(now) {
+			continue
+		}
+
+		t, err := n.SchedulingMarkTimestamp()
+		if err != nil || now.Sub(t) < s.config.MaxDrainDuration {
+			continue
+		}
+
+		servers := s.activeServers(n.Pods)
+		for i := range servers {
+			if err = s.signalSessionDeadline(n, &servers[i], now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// signalSessionDeadline annotates the pod with its deletion deadline, or deletes it if the deadline is passed
+func (s *Scaler) signalSessionDeadline(n *Node, pod *v1.Pod, now time.Time) error {
+	if pod.DeletionTimestamp != nil {
+		return nil
+	}
+
+	deadline, err := time.Parse(time.RFC3339, pod.Annotations[sessionDeadlineAnnotation])
+	if err != nil {
+		// the pods without (or with an invalid) deadline are signaled with a new deadline
+		deadline = now.Add(s.config.SessionDeadlineGracePeriod)
+		s.config.Logger.Infof("node %s is draining for more than %s, pod %s/%s is signaled to finish until %s", n.N.Name, s.config.MaxDrainDuration, pod.Namespace, pod.Name, deadline.Format(time.RFC3339))
+		return s.k8s.AnnotatePod(context.Background(), pod, sessionDeadlineAnnotation, deadline.Format(time.RFC3339))
+	}
+
+	if now.Before(deadline) {
+		return nil
+	}
+
+	s.config.Logger.Infof("pod %s/%s on node %s passed the session deadline, deleting", pod.Namespace, pod.Name, n.N.Name)
+	err = s.k8s.DeletePod(context.Background(), pod)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package kubescaler
+
+import (
+	"encoding/json"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sT "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func TestScaler_enforceSessionDeadline(t *testing.T) {
+	nodes := newNodeList(2, false)
+	pods := newPodList(repeatRequestPod(2, requestPod{cpuResource: "0.1", isDedicatedServer: true}))
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): pods,
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(2, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+
+	clientSet.AddReactor("patch", "pods", func(a k8sT.Action) (bool, runtime.Object, error) {
+		pa := a.(k8sT.PatchAction)
+		var patch v1.Pod
+		if err := json.Unmarshal(pa.GetPatch(), &patch); err != nil {
+			return true, nil, err
+		}
+		for i := range pods.Items {
+			if pods.Items[i].Name == pa.GetName() {
+				pods.Items[i].Annotations = patch.Annotations
+			}
+		}
+		return true, nil, nil
+	})
+
+	var deleted []string
+	clientSet.AddReactor("delete", "pods", func(a k8sT.Action) (bool, runtime.Object, error) {
+		deleted = append(deleted, a.(k8sT.DeleteAction).GetName())
+		return true, nil, nil
+	})
+
+	srv := NewScaler(nil, NewK8S(clientSet), &Config{
+		NodeSelector:               nodeSelector,
+		PodCPURequest:              100,
+		PodLabelName:               podLabelName,
+		PodLabelValue:              podLabelValue,
+		MaxDrainDuration:           time.Hour,
+		SessionDeadlineGracePeriod: 5 * time.Minute,
+	})
+
+	list, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	// the first node is draining for longer than the max drain duration, the second one just started
+	for i, d := range []time.Duration{2 * time.Hour, time.Minute} {
+		err = srv.markNodeAsUnschedulable(list.Nodes[i])
+		if err != nil {
+			t.Logf("expected node to be marked, got err: %s", err)
+			t.FailNow()
+		}
+		ts, _ := time.Now().Add(-d).MarshalText()
+		nodes.Items[i].Annotations[timestampAnnotation] = string(ts)
+	}
+
+	enforce := func() {
+		list, err := srv.nodes()
+		if err != nil {
+			t.Logf("expected node list, got err: %s", err)
+			t.FailNow()
+		}
+
+		err = srv.enforceSessionDeadline(list)
+		if err != nil {
+			t.Logf("expected session deadline enforcement, got err: %s", err)
+			t.FailNow()
+		}
+	}
+
+	enforce()
+	for _, p := range pods.Items {
+		if _, ok := p.Annotations[sessionDeadlineAnnotation]; !ok {
+			t.Logf("expected pod %s to be signaled", p.Name)
+			t.FailNow()
+		}
+	}
+	if len(deleted) != 0 {
+		t.Logf("expected no pod to be deleted before the deadline, got %d", len(deleted))
+		t.FailNow()
+	}
+
+	pods.Items[0].Annotations[sessionDeadlineAnnotation] = time.Now().Add(-time.Second).Format(time.RFC3339)
+	pods.Items[1].Annotations[sessionDeadlineAnnotation] = "invalid"
+	enforce()
+	if len(deleted) != 1 || deleted[0] != pods.Items[0].Name {
+		t.Logf("expected only the pod passed the deadline to be deleted, got %v", deleted)
+		t.FailNow()
+	}
+
+	if deadline, err := time.Parse(time.RFC3339, pods.Items[1].Annotations[sessionDeadlineAnnotation]); err != nil || !deadline.After(time.Now()) {
+		t.Logf("expected the invalid deadline to be replaced, got %s", pods.Items[1].Annotations[sessionDeadlineAnnotation])
+		t.FailNow()
+	}
+}
@@ -0,0 +1,94 @@
+package kubescaler
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"time"
+)
+
+// drainNode evicts the pods which are not dedicated servers from the node without waiting for them, the drain start is
+// recorded so the node is checked again in the next passes. It returns true once the node is drained, a node which is not
+// drained in the drain timeout is skipped for another drain timeout before the drain is retried
+func (s *Scaler) drainNode(node *Node, now time.Time) (bool, error) {
+	evictable := s.evictablePods(node.Pods)
+	if len(evictable) == 0 {
+		delete(s.draining, node.N.UID)
+		return true, nil
+	}
+
+	start, ok := s.draining[node.N.UID]
+	if !ok {
+		start = now
+		s.draining[node.N.UID] = start
+	}
+
+	if now.Before(start) {
+		s.config.Logger.Debugf("node %s drain is retried in %s", node.N.Name, start.Sub(now))
+		return false, nil
+	}
+
+	if now.Sub(start) >= s.config.DrainTimeout {
+		s.config.Logger.Infof("node %s is not drained in %s, %d pod(s) remaining, skipped for %s", node.N.Name, s.config.DrainTimeout, len(evictable), s.config.DrainTimeout)
+		s.draining[node.N.UID] = now.Add(s.config.DrainTimeout)
+		return false, nil
+	}
+
+	var gracePeriod *int64
+	if s.config.DrainGracePeriod > 0 {
+		seconds := int64(s.config.DrainGracePeriod.Seconds())
+		gracePeriod = &seconds
+	}
+
+	s.config.Logger.Debugf("draining node %s, %d pod(s) remaining", node.N.Name, len(evictable))
+	for i := range evictable {
+		pod := &evictable[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		err := s.k8s.EvictPod(context.Background(), pod, gracePeriod)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+		case apierrors.IsTooManyRequests(err):
+			s.config.Logger.Debugf("eviction of pod %s/%s is blocked by disruption budget", pod.Namespace, pod.Name)
+		default:
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// evictablePods filters out the dedicated server (except the idle ones), mirror, daemon set and terminated pods
+func (s *Scaler) evictablePods(pods []v1.Pod) []v1.Pod {
+	var evictable []v1.Pod
+	for _, p := range pods {
+		if s.isServerPod(p) && !s.isIdleServer(p) {
+			continue
+		}
+
+		if _, ok := p.ObjectMeta.Annotations[v1.MirrorPodAnnotationKey]; ok {
+			continue
+		}
+
+		if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+
+		if isDaemonSetPod(p) {
+			continue
+		}
+
+		evictable = append(evictable, p)
+	}
+	return evictable
+}
+
+func isDaemonSetPod(p v1.Pod) bool {
+	for _, o := range p.ObjectMeta.OwnerReferences {
+		if o.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package kubescaler
+
+import (
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8sT "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func TestScaler_drainNode(t *testing.T) {
+	tests := []struct {
+		name     string
+		blocked  bool
+		passes   []time.Duration
+		expected []bool
+	}{
+		{
+			name:     "drained",
+			passes:   []time.Duration{0, time.Second},
+			expected: []bool{false, true},
+		},
+		{
+			name:     "blocked_by_disruption_budget",
+			blocked:  true,
+			passes:   []time.Duration{0, time.Second, time.Minute, 90 * time.Second, 2 * time.Minute},
+			expected: []bool{false, false, false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientSet := &fake.Clientset{}
+			srv := NewScaler(nil, NewK8S(clientSet), &Config{
+				PodLabelName:  podLabelName,
+				PodLabelValue: podLabelValue,
+				DrainTimeout:  time.Minute,
+			})
+
+			pods := newPodList(repeatRequestPod(2, requestPod{
+				cpuResource: "0.1",
+			}))
+			pods.Items[1].OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "monitoring"}}
+
+			var evicted, attempts int
+			clientSet.AddReactor("create", "pods", func(a k8sT.Action) (bool, runtime.Object, error) {
+				if a.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				attempts++
+				if tt.blocked {
+					return true, nil, apierrors.NewTooManyRequests("disruption budget", 10)
+				}
+
+				evicted++
+				name := a.(k8sT.CreateAction).GetObject().(metav1.Object).GetName()
+				var remaining []v1.Pod
+				for _, p := range pods.Items {
+					if p.Name != name {
+						remaining = append(remaining, p)
+					}
+				}
+				pods.Items = remaining
+				return true, nil, nil
+			})
+
+			node := &Node{N: &newNodeList(1, false).Items[0]}
+			start := time.Now()
+			for i, d := range tt.passes {
+				node.Pods = pods.Items
+				drained, err := srv.drainNode(node, start.Add(d))
+				if err != nil {
+					t.Logf("expected drain, got err: %s", err)
+					t.FailNow()
+				}
+
+				if drained != tt.expected[i] {
+					t.Logf("expected drained %t in pass %d, got %t", tt.expected[i], i, drained)
+					t.FailNow()
+				}
+			}
+
+			if tt.blocked {
+				// the timed out drain is skipped for the drain timeout, then retried
+				if attempts != 3 {
+					t.Logf("expected 3 eviction attempts, got %d", attempts)
+					t.FailNow()
+				}
+
+				if _, ok := srv.draining[node.N.UID]; !ok {
+					t.Logf("expected the blocked drain to be kept")
+					t.FailNow()
+				}
+				return
+			}
+
+			if evicted != 1 {
+				t.Logf("expected 1 eviction, got %d", evicted)
+				t.FailNow()
+			}
+
+			if _, ok := srv.draining[node.N.UID]; ok {
+				t.Logf("expected the drain to be forgotten")
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestScaler_evictablePods(t *testing.T) {
+	srv := NewScaler(nil, nil, &Config{
+		PodLabelName:  podLabelName,
+		PodLabelValue: podLabelValue,
+	})
+
+	pods := newPodList(append(repeatRequestPod(1, requestPod{cpuResource: "0.1", isDedicatedServer: true}), repeatRequestPod(3, requestPod{cpuResource: "0.1"})...))
+	pods.Items[2].Annotations = map[string]string{v1.MirrorPodAnnotationKey: "mirror"}
+	pods.Items[3].Status.Phase = v1.PodSucceeded
+
+	evictable := srv.evictablePods(pods.Items)
+	if len(evictable) != 1 || evictable[0].Name != pods.Items[1].Name {
+		t.Logf("expected only %s to be evictable, got %d pods", pods.Items[1].Name, len(evictable))
+		t.FailNow()
+	}
+}
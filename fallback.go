@@ -0,0 +1,110 @@
+package kubescaler
+
+import (
+	"context"
+	"k8s.io/apimachinery/pkg/labels"
+	"time"
+)
+
+// checkFallbackConfig warns about a fallback node pool which can't be scaled up
+func checkFallbackConfig(c *Config) {
+	if c.FallbackProvider != nil && c.FallbackMaximumNode <= 0 {
+		c.Logger.Errorf("fallback maximum node pool size is not set, the fallback node pool is disabled")
+	}
+}
+
+// isFallbackNode reports whether the node belongs to the fallback node pool
+func (s *Scaler) isFallbackNode(n *Node) bool {
+	if s.config.FallbackProvider == nil || s.config.FallbackNodeSelector == "" {
+		return false
+	}
+
+	selector, err := labels.Parse(s.config.FallbackNodeSelector)
+	if err != nil {
+		s.config.Logger.Errorf("invalid fallback node selector: %s", err)
+		return false
+	}
+	return selector.Matches(labels.Set(n.N.ObjectMeta.Labels))
+}
+
+// splitNodes splits the nodes of the node pool and the fallback node pool
+func (s *Scaler) splitNodes(nodes *NodeList) (*NodeList, *NodeList) {
+	primary, fallback := &NodeList{}, &NodeList{}
+	for _, n := range nodes.Nodes {
+		if s.isFallbackNode(n) {
+			fallback.Nodes = append(fallback.Nodes, n)
+		} else {
+			primary.Nodes = append(primary.Nodes, n)
+		}
+	}
+	return primary, fallback
+}
+
+// fallbackEnabled reports whether the fallback node pool can be scaled up
+func (s *Scaler) fallbackEnabled() bool {
+	return s.config.FallbackProvider != nil && s.config.FallbackMaximumNode > 0
+}
+
+// isFallingBack reports whether the node pool failed to provide nodes in the FallbackDuration
+func (s *Scaler) isFallingBack() bool {
+	return s.fallbackEnabled() && !s.primaryFailedAt.IsZero() && time.Since(s.primaryFailedAt) < s.config.FallbackDuration
+}
+
+// primaryFailed records the node pool failure to provide nodes, the next scale ups use the fallback node pool
+func (s *Scaler) primaryFailed() {
+	if !s.fallbackEnabled() {
+		return
+	}
+
+	if !s.isFallingBack() {
+		s.config.Logger.Infof("node pool failed to provide nodes, scaling up the fallback node pool for %s", s.config.FallbackDuration)
+	}
+	s.primaryFailedAt = time.Now()
+}
+
+// increaseFallbackSize adds the needed nodes to the fallback node pool, capped to the fallback maximum node
+func (s *Scaler) increaseFallbackSize(fallback *NodeList, needed int) error {
+	if needed <= 0 {
+		return nil
+	}
+
+	size := len(fallback.AvailableNodes()) + needed
+	if size > s.config.FallbackMaximumNode {
+		size = s.config.FallbackMaximumNode
+	}
+	if size <= len(fallback.Nodes) {
+		s.config.Logger.Debugf("fallback node pool size is %d, needs %d node(s) more", len(fallback.Nodes), needed)
+		return nil
+	}
+
+	s.config.Logger.Infof("request to increase fallback node pool size to %d", size)
+	err := s.config.FallbackProvider.ResizeNode(context.Background(), size)
+	if err != nil {
+		return err
+	}
+	s.lastScaleUp = time.Now()
+	return nil
+}
+
+// deleteNodes deletes the nodes from their node pools
+func (s *Scaler) deleteNodes(nodes []*Node) error {
+	var primary, fallback []string
+	for _, n := range nodes {
+		if s.isFallbackNode(n) {
+			fallback = append(fallback, n.N.Name)
+		} else {
+			primary = append(primary, n.N.Name)
+		}
+	}
+
+	if len(primary) > 0 {
+		if err := s.npm.DeleteNodes(context.Background(), primary); err != nil {
+			return err
+		}
+	}
+
+	if len(fallback) > 0 {
+		return s.config.FallbackProvider.DeleteNodes(context.Background(), fallback)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package kubescaler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/theredrad/kubescaler/mocks"
+	v1 "k8s.io/api/core/v1"
+	"testing"
+	"time"
+)
+
+func TestScaler_scaleFallback(t *testing.T) {
+	nodes := newNodeList(3, false)
+	nodes.Items[2].Spec.Taints = []v1.Taint{{Key: "aws-node-termination-handler/spot-itn", Effect: v1.TaintEffectNoSchedule}}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(10, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(10, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+
+	ctrl := gomock.NewController(t)
+	npm := mocks.NewMockNodePoolProvider(ctrl)
+	// the interrupted node is replaced, but the node pool has no capacity
+	npm.EXPECT().ResizeNode(gomock.Any(), 4).Return(errors.New("capacity not available")).Times(1)
+	fallback := mocks.NewMockNodePoolProvider(ctrl)
+	fallback.EXPECT().ResizeNode(gomock.Any(), 1).Return(nil).Times(2)
+
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:         nodeSelector,
+		MinimumNode:          1,
+		MaximumNode:          6,
+		PodCPURequest:        100,
+		BufferSlotSize:       4,
+		PodLabelName:         podLabelName,
+		PodLabelValue:        podLabelValue,
+		InterruptionTaints:   []string{"aws-node-termination-handler/spot-itn"},
+		FallbackProvider:     fallback,
+		FallbackNodeSelector: "pool=on-demand",
+		FallbackMaximumNode:  4,
+		FallbackDuration:     time.Minute,
+	})
+
+	// the second pass scales the fallback node pool up without retrying the node pool
+	for i := 0; i < 2; i++ {
+		err := srv.scale()
+		if err != nil {
+			t.Logf("expected scale, got err: %s", err)
+			t.FailNow()
+		}
+	}
+
+	if status := srv.Status(); status.InterruptedNodes != 1 || status.AvailableSlot != 0 {
+		t.Logf("expected 1 interrupted node and no available slot, got %d interrupted nodes and %d slots", status.InterruptedNodes, status.AvailableSlot)
+		t.FailNow()
+	}
+}
+
+func TestScaler_deleteExtraFallbackNodes(t *testing.T) {
+	nodes := newNodeList(3, false)
+	nodes.Items[2].Labels["pool"] = "on-demand"
+	for i := 1; i < 3; i++ {
+		n := Node{N: &nodes.Items[i]}
+		if err := n.MarkAsUnschedulable(); err != nil {
+			t.Logf("expected marking node as unschedulable, got err: %s", err)
+			t.FailNow()
+		}
+	}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(5, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+
+	ctrl := gomock.NewController(t)
+	// the node pool is at the minimum size, only the fallback node is deleted
+	npm := mocks.NewMockNodePoolProvider(ctrl)
+	fallback := mocks.NewMockNodePoolProvider(ctrl)
+	fallback.EXPECT().DeleteNodes(gomock.Any(), []string{fmt.Sprintf(fmtNodeName, "2")}).Return(nil).Times(1)
+
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:         nodeSelector,
+		MinimumNode:          2,
+		PodCPURequest:        100,
+		PodLabelName:         podLabelName,
+		PodLabelValue:        podLabelValue,
+		FallbackProvider:     fallback,
+		FallbackNodeSelector: "pool=on-demand",
+		FallbackMaximumNode:  2,
+		FallbackDuration:     time.Minute,
+	})
+
+	if err := srv.deleteExtraNodes(); err != nil {
+		t.Logf("expected deleting extra nodes, got err: %s", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,211 @@
+package kubescaler
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// History stores the used slot time series
+type History interface {
+	Record(t time.Time, used int64) error
+	// At returns the sample nearest to the given time within the tolerance
+	At(t time.Time, tolerance time.Duration) (int64, bool)
+}
+
+// Forecaster predicts the used slots at a future time
+type Forecaster interface {
+	Forecast(h History, now time.Time, used int64, at time.Time) (int64, bool)
+}
+
+type sample struct {
+	t    time.Time
+	used int64
+}
+
+// FileHistory keeps the samples in memory and appends them to a local file to survive restarts
+type FileHistory struct {
+	mu        sync.Mutex
+	path      string
+	retention time.Duration
+	samples   []sample
+	trimmed   int
+}
+
+// NewFileHistory loads the samples of the file and drops the ones older than the retention
+func NewFileHistory(path string, retention time.Duration) (*FileHistory, error) {
+	h := &FileHistory{
+		path:      path,
+		retention: retention,
+	}
+
+	f, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var unix, used int64
+			if _, err := fmt.Sscanf(scanner.Text(), "%d %d", &unix, &used); err != nil {
+				continue
+			}
+			h.samples = append(h.samples, sample{t: time.Unix(unix, 0), used: used})
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(h.samples, func(i, j int) bool {
+		return h.samples[i].t.Before(h.samples[j].t)
+	})
+	h.trim(time.Now())
+	return h, h.compact()
+}
+
+func (h *FileHistory) Record(t time.Time, used int64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.samples = append(h.samples, sample{t: t, used: used})
+	h.trim(t)
+	if h.trimmed > len(h.samples) {
+		return h.compact()
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%d %d\n", t.Unix(), used)
+	return err
+}
+
+func (h *FileHistory) At(t time.Time, tolerance time.Duration) (int64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := sort.Search(len(h.samples), func(i int) bool {
+		return !h.samples[i].t.Before(t)
+	})
+
+	var nearest *sample
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(h.samples) {
+			continue
+		}
+		if nearest == nil || absDuration(h.samples[j].t.Sub(t)) < absDuration(nearest.t.Sub(t)) {
+			nearest = &h.samples[j]
+		}
+	}
+
+	if nearest == nil || absDuration(nearest.t.Sub(t)) > tolerance {
+		return 0, false
+	}
+	return nearest.used, true
+}
+
+func (h *FileHistory) trim(now time.Time) {
+	i := sort.Search(len(h.samples), func(i int) bool {
+		return now.Sub(h.samples[i].t) <= h.retention
+	})
+	h.samples = h.samples[i:]
+	h.trimmed += i
+}
+
+// compact rewrites the file with the retained samples
+func (h *FileHistory) compact() error {
+	tmp := h.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, s := range h.samples {
+		fmt.Fprintf(w, "%d %d\n", s.t.Unix(), s.used)
+	}
+	if err = w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	h.trimmed = 0
+	return os.Rename(tmp, h.path)
+}
+
+// SeasonalForecaster predicts the used slots as the used slots one season ago (ex: same time last week) plus the trend since then
+type SeasonalForecaster struct {
+	Season    time.Duration
+	Tolerance time.Duration
+}
+
+func (f *SeasonalForecaster) Forecast(h History, now time.Time, used int64, at time.Time) (int64, bool) {
+	past, ok := h.At(at.Add(-f.Season), f.Tolerance)
+	if !ok {
+		return 0, false
+	}
+
+	base, ok := h.At(now.Add(-f.Season), f.Tolerance)
+	if !ok {
+		return 0, false
+	}
+
+	forecast := past + used - base
+	if forecast < 0 {
+		forecast = 0
+	}
+	return forecast, true
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// recordUsage adds the used slots to the history at most once per history interval
+func (s *Scaler) recordUsage(used int64) {
+	if s.config.History == nil {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(s.lastRecord) < s.config.HistoryInterval {
+		return
+	}
+
+	err := s.config.History.Record(now, used)
+	if err != nil {
+		s.config.Logger.Errorf("error while recording used slot history: %s", err)
+		return
+	}
+	s.lastRecord = now
+}
+
+// forecastBufferSize returns the buffer needed for the used slots forecast at the forecast horizon
+func (s *Scaler) forecastBufferSize(used int64) (int64, bool) {
+	if s.config.History == nil || s.config.Forecaster == nil || s.config.ForecastHorizon <= 0 {
+		return 0, false
+	}
+
+	now := time.Now()
+	forecast, ok := s.config.Forecaster.Forecast(s.config.History, now, used, now.Add(s.config.ForecastHorizon))
+	if !ok {
+		return 0, false
+	}
+
+	s.config.Logger.Debugf("used slot forecast in %s: %d", s.config.ForecastHorizon, forecast)
+	return forecast - used, true
+}
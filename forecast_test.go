@@ -0,0 +1,53 @@
+package kubescaler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	now := time.Now().Truncate(time.Second)
+
+	h, err := NewFileHistory(path, 8*24*time.Hour)
+	if err != nil {
+		t.Logf("expected history, got err: %s", err)
+		t.FailNow()
+	}
+
+	for _, s := range []sample{
+		{t: now.Add(-9 * 24 * time.Hour), used: 1},
+		{t: now.Add(-7 * 24 * time.Hour), used: 10},
+		{t: now.Add(-7*24*time.Hour + 30*time.Minute), used: 50},
+	} {
+		if err = h.Record(s.t, s.used); err != nil {
+			t.Logf("expected sample recorded, got err: %s", err)
+			t.FailNow()
+		}
+	}
+
+	// reload from the file
+	h, err = NewFileHistory(path, 8*24*time.Hour)
+	if err != nil {
+		t.Logf("expected history, got err: %s", err)
+		t.FailNow()
+	}
+
+	if _, ok := h.At(now.Add(-9*24*time.Hour), time.Minute); ok {
+		t.Logf("expected expired sample to be dropped")
+		t.FailNow()
+	}
+
+	if used, ok := h.At(now.Add(-7*24*time.Hour+time.Minute), 2*time.Minute); !ok || used != 10 {
+		t.Logf("expected nearest sample 10, got %d (found: %t)", used, ok)
+		t.FailNow()
+	}
+
+	f := &SeasonalForecaster{Season: 7 * 24 * time.Hour, Tolerance: 2 * time.Minute}
+	forecast, ok := f.Forecast(h, now, 20, now.Add(30*time.Minute))
+	if !ok || forecast != 60 {
+		t.Logf("expected forecast 60, got %d (found: %t)", forecast, ok)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,143 @@
+package kubescaler
+
+import (
+	"context"
+	"github.com/theredrad/kubescaler/nodepoolmanager"
+	"time"
+)
+
+// removeUnhealthyNodes deletes the nodes which never became ready in NodeBootTimeout and the empty nodes which are not ready
+// for UnhealthyNodeTimeout, the node pool is resized back by the next scale passes. The protected nodes are kept, and the
+// deletions are limited to MaxConcurrentDeletions in flight and the minimum node pool size
+func (s *Scaler) removeUnhealthyNodes(nodes *NodeList) error {
+	if s.config.NodeBootTimeout <= 0 && s.config.UnhealthyNodeTimeout <= 0 {
+		return nil
+	}
+
+	minimumNode := s.minimumNode()
+	primary, _ := s.splitNodes(nodes)
+	l := len(primary.Nodes)
+	for _, n := range primary.Nodes {
+		if s.deleting[n.N.UID] {
+			l--
+		}
+	}
+
+	now := time.Now()
+	registered := make(map[string]bool)
+	var deleteNodes []*Node
+	for _, node := range nodes.Nodes {
+		registered[node.N.Name] = true
+	}
+
+	for _, node := range nodes.Nodes {
+		if node.IsReady() || s.deleting[node.N.UID] || len(s.activeServers(node.Pods)) > 0 {
+			continue
+		}
+
+		since := node.NotReadySince()
+		timeout := s.config.UnhealthyNodeTimeout
+		// the node has never been ready since it joined the cluster
+		booting := since.Sub(node.N.CreationTimestamp.Time) < s.config.NodeBootTimeout
+		if booting {
+			timeout = s.config.NodeBootTimeout
+		}
+
+		if timeout <= 0 || now.Sub(since) < timeout {
+			continue
+		}
+
+		if node.IsScaleDownDisabled() || node.IsProtected(now) {
+			s.config.Logger.Debugf("node %s is not ready since %s, but it's protected from deletion", node.N.Name, since)
+			continue
+		}
+
+		if s.config.MaxConcurrentDeletions > 0 && s.inFlightDeletions()+len(deleteNodes) >= s.config.MaxConcurrentDeletions {
+			s.config.Logger.Debugf("deletion limited to %d node(s) in flight", s.config.MaxConcurrentDeletions)
+			break
+		}
+
+		isFallback := s.isFallbackNode(node)
+		if !isFallback && l <= minimumNode {
+			s.config.Logger.Debugf("node %s is not ready since %s, but the node pool is at the minimum size", node.N.Name, since)
+			continue
+		}
+
+		s.config.Logger.Infof("node %s is not ready since %s, replacing", node.N.Name, since)
+		deleteNodes = append(deleteNodes, node)
+		if !isFallback {
+			l--
+			if booting {
+				s.primaryFailed()
+			}
+		}
+	}
+
+	err := s.deleteNodes(deleteNodes)
+	if err != nil {
+		return err
+	}
+	for _, node := range deleteNodes {
+		s.deleting[node.N.UID] = true
+	}
+
+	unregistered, err := s.unregisteredNodes(registered, now)
+	if err != nil {
+		s.config.Logger.Errorf("error while listing node pool nodes: %s", err)
+	}
+	if left := s.config.MaxConcurrentDeletions - s.inFlightDeletions(); s.config.MaxConcurrentDeletions > 0 && len(unregistered) > left {
+		if left < 0 {
+			left = 0
+		}
+		s.config.Logger.Debugf("deletion limited to %d node(s) in flight", s.config.MaxConcurrentDeletions)
+		unregistered = unregistered[:left]
+	}
+	if len(unregistered) == 0 {
+		return nil
+	}
+
+	s.primaryFailed()
+	err = s.npm.DeleteNodes(context.Background(), unregistered)
+	if err != nil {
+		return err
+	}
+	for _, name := range unregistered {
+		s.deletingUnregistered[name] = true
+	}
+	return nil
+}
+
+// unregisteredNodes returns the node pool members which are not registered in the cluster in NodeBootTimeout,
+// it requires the provider to implement nodepoolmanager.NodeLister
+func (s *Scaler) unregisteredNodes(registered map[string]bool, now time.Time) ([]string, error) {
+	lister, ok := s.npm.(nodepoolmanager.NodeLister)
+	if !ok || s.config.NodeBootTimeout <= 0 {
+		return nil, nil
+	}
+
+	poolNodes, err := lister.Nodes(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]bool)
+	var names []string
+	for _, n := range poolNodes {
+		exists[n.Name] = true
+		if registered[n.Name] || s.deletingUnregistered[n.Name] || n.CreatedAt.IsZero() {
+			continue
+		}
+
+		if now.Sub(n.CreatedAt) > s.config.NodeBootTimeout {
+			s.config.Logger.Infof("node %s is not registered since %s (state: %s), replacing", n.Name, n.CreatedAt, n.State)
+			names = append(names, n.Name)
+		}
+	}
+
+	for name := range s.deletingUnregistered {
+		if !exists[name] {
+			delete(s.deletingUnregistered, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,150 @@
+package kubescaler
+
+import (
+	"context"
+	"fmt"
+	"github.com/theredrad/kubescaler/nodepoolmanager"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+type nodeListerMock struct {
+	nodepoolmanager.Provider
+	nodes []nodepoolmanager.Node
+}
+
+func (m *nodeListerMock) Nodes(ctx context.Context) ([]nodepoolmanager.Node, error) {
+	return m.nodes, nil
+}
+
+func TestScaler_removeUnhealthyNodes(t *testing.T) {
+	now := time.Now()
+	nodes := newNodeList(5, false)
+	for i := range nodes.Items {
+		nodes.Items[i].CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+	}
+
+	// stuck booting since creation
+	nodes.Items[1].CreationTimestamp = metav1.NewTime(now.Add(-20 * time.Minute))
+	nodes.Items[1].Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: nodes.Items[1].CreationTimestamp}}
+	// not ready recently
+	nodes.Items[2].Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-time.Minute))}}
+	// not ready for long, but runs a dedicated server
+	nodes.Items[3].Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-30 * time.Minute))}}
+	// not ready for long, but protected from scale down
+	nodes.Items[4].Annotations = map[string]string{"kubescaler/scale-down-disabled": "true"}
+	nodes.Items[4].Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-30 * time.Minute))}}
+
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "3"): newPodList(repeatRequestPod(1, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+
+	npm := &nodeListerMock{
+		Provider: newNodePoolManagerMock(t, clientSet, nodes, false),
+		nodes: []nodepoolmanager.Node{
+			{Name: nodes.Items[0].Name, CreatedAt: now.Add(-time.Hour)},
+			{Name: "test-node-provisioning", CreatedAt: now.Add(-time.Minute)},
+			{Name: "test-node-stuck", State: "provisioning", CreatedAt: now.Add(-30 * time.Minute)},
+		},
+	}
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:         nodeSelector,
+		PodLabelName:         podLabelName,
+		PodLabelValue:        podLabelValue,
+		NodeBootTimeout:      10 * time.Minute,
+		UnhealthyNodeTimeout: 5 * time.Minute,
+	})
+
+	list, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	err = srv.removeUnhealthyNodes(list)
+	if err != nil {
+		t.Logf("expected removing unhealthy nodes, got err: %s", err)
+		t.FailNow()
+	}
+
+	if len(nodes.Items) != 4 || nodes.Items[1].Name != fmt.Sprintf(fmtNodeName, "2") {
+		t.Logf("expected only the booting node to be deleted, got %d nodes", len(nodes.Items))
+		t.FailNow()
+	}
+
+	if !srv.deleting[list.Nodes[1].N.UID] || len(srv.deleting) != 1 {
+		t.Logf("expected the booting node deletion to be in flight, got %d deletions", len(srv.deleting))
+		t.FailNow()
+	}
+
+	if !srv.deletingUnregistered["test-node-stuck"] || len(srv.deletingUnregistered) != 1 {
+		t.Logf("expected only the stuck unregistered node to be deleted, got %v", srv.deletingUnregistered)
+		t.FailNow()
+	}
+}
+
+func TestScaler_removeUnhealthyNodesLimits(t *testing.T) {
+	tests := []struct {
+		name                   string
+		minimumNode            int
+		maxConcurrentDeletions int
+		expectedNodes          int
+	}{
+		{
+			name:          "unlimited",
+			expectedNodes: 0,
+		},
+		{
+			name:          "minimum_node",
+			minimumNode:   3,
+			expectedNodes: 3,
+		},
+		{
+			name:                   "concurrent_deletions",
+			maxConcurrentDeletions: 1,
+			expectedNodes:          3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			nodes := newNodeList(4, false)
+			for i := range nodes.Items {
+				nodes.Items[i].CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+				nodes.Items[i].Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-30 * time.Minute))}}
+			}
+			clientSet := newFakeCluster(nodes, nil)
+			srv := NewScaler(newNodePoolManagerMock(t, clientSet, nodes, false), NewK8S(clientSet), &Config{
+				NodeSelector:           nodeSelector,
+				MinimumNode:            tt.minimumNode,
+				PodLabelName:           podLabelName,
+				PodLabelValue:          podLabelValue,
+				UnhealthyNodeTimeout:   5 * time.Minute,
+				MaxConcurrentDeletions: tt.maxConcurrentDeletions,
+			})
+
+			// the second pass waits for the deletions in flight
+			for i := 0; i < 2; i++ {
+				list, err := srv.nodes()
+				if err != nil {
+					t.Logf("expected node list, got err: %s", err)
+					t.FailNow()
+				}
+
+				err = srv.removeUnhealthyNodes(list)
+				if err != nil {
+					t.Logf("expected removing unhealthy nodes, got err: %s", err)
+					t.FailNow()
+				}
+			}
+
+			if len(nodes.Items) != tt.expectedNodes {
+				t.Logf("expected %d nodes, got %d nodes", tt.expectedNodes, len(nodes.Items))
+				t.FailNow()
+			}
+		})
+	}
+}
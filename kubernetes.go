@@ -2,19 +2,30 @@ package kubescaler
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/apimachinery/pkg/watch"
 	"sort"
 )
 
 type Kubernetes interface {
 	Nodes(ctx context.Context, selector string) (*NodeList, error)
 	UpdateNode(ctx context.Context, node *Node) error
+	NodePods(ctx context.Context, nodeName string) (*v1.PodList, error)
+	PendingPods(ctx context.Context, labelSelector string) (*v1.PodList, error)
+	EvictPod(ctx context.Context, pod *v1.Pod, gracePeriodSeconds *int64) error
+	DeletePod(ctx context.Context, pod *v1.Pod) error
+	AnnotatePod(ctx context.Context, pod *v1.Pod, key, value string) error
+	RemovePodAnnotation(ctx context.Context, pod *v1.Pod, key string) error
 	NewPodWatcher(ctx context.Context, namespace, labelSelector string) (*PodWatcher, error)
 }
 
@@ -22,20 +33,29 @@ type K8S struct {
 	i kubernetes.Interface
 }
 
-func ClientSet(masterURL, kubeConfigPath string) (kubernetes.Interface, error) {
-	var c *rest.Config
-	var err error
+func restConfig(masterURL, kubeConfigPath string) (*rest.Config, error) {
 	if masterURL == "" && kubeConfigPath == "" {
-		c, err = rest.InClusterConfig()
-	} else {
-		c, err = clientcmd.BuildConfigFromFlags(masterURL, kubeConfigPath)
+		return rest.InClusterConfig()
 	}
+	return clientcmd.BuildConfigFromFlags(masterURL, kubeConfigPath)
+}
+
+func ClientSet(masterURL, kubeConfigPath string) (kubernetes.Interface, error) {
+	c, err := restConfig(masterURL, kubeConfigPath)
 	if err != nil {
 		return nil, err
 	}
 	return kubernetes.NewForConfig(c)
 }
 
+func DynamicClient(masterURL, kubeConfigPath string) (dynamic.Interface, error) {
+	c, err := restConfig(masterURL, kubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(c)
+}
+
 func NewK8SFromKubeConfig(masterURL, kubeConfigPath string) (*K8S, error) {
 	i, err := ClientSet(masterURL, kubeConfigPath)
 	if err != nil {
@@ -69,6 +89,19 @@ func (k *K8S) UpdateNode(ctx context.Context, node *Node) error {
 	return nil
 }
 
+func (k *K8S) SecretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	secret, err := k.i.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret %s/%s", key, namespace, name)
+	}
+	return string(v), nil
+}
+
 func (k *K8S) NodePods(ctx context.Context, nodeName string) (*v1.PodList, error) {
 	fs, err := fields.ParseSelector("spec.nodeName=" + nodeName)
 	if err != nil {
@@ -79,6 +112,62 @@ func (k *K8S) NodePods(ctx context.Context, nodeName string) (*v1.PodList, error
 	return pods, err
 }
 
+func (k *K8S) PendingPods(ctx context.Context, labelSelector string) (*v1.PodList, error) {
+	fs, err := fields.ParseSelector("status.phase=" + string(v1.PodPending))
+	if err != nil {
+		return nil, err
+	}
+
+	return k.i.CoreV1().Pods(v1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fs.String(),
+	})
+}
+
+// EvictPod evicts the pod using the Eviction API, so the PodDisruptionBudgets are respected
+func (k *K8S) EvictPod(ctx context.Context, pod *v1.Pod, gracePeriodSeconds *int64) error {
+	return k.i.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: gracePeriodSeconds,
+		},
+	})
+}
+
+// DeletePod deletes the pod gracefully using the pod termination grace period
+func (k *K8S) DeletePod(ctx context.Context, pod *v1.Pod) error {
+	return k.i.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+}
+
+// AnnotatePod sets the pod annotation using a merge patch
+func (k *K8S) AnnotatePod(ctx context.Context, pod *v1.Pod, key, value string) error {
+	return k.patchPodAnnotation(ctx, pod, key, value)
+}
+
+// RemovePodAnnotation removes the pod annotation using a merge patch
+func (k *K8S) RemovePodAnnotation(ctx context.Context, pod *v1.Pod, key string) error {
+	return k.patchPodAnnotation(ctx, pod, key, nil)
+}
+
+func (k *K8S) patchPodAnnotation(ctx context.Context, pod *v1.Pod, key string, value interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				key: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = k.i.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
 func (k *K8S) NewPodWatcher(ctx context.Context, namespace, labelSelector string) (*PodWatcher, error) {
 	w, err := k.i.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{
 		LabelSelector: labelSelector,
@@ -88,7 +177,7 @@ func (k *K8S) NewPodWatcher(ctx context.Context, namespace, labelSelector string
 	}
 
 	return &PodWatcher{
-		w: w,
+		w:      w,
 		Events: make(chan watch.Event),
 	}, nil
 }
@@ -2,11 +2,32 @@ package kubescaler
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"strconv"
 	"time"
 )
 
 const (
 	timestampAnnotation = "kubescaler/timestamp"
+
+	// ownerAnnotation marks the nodes made unschedulable by the scaler, other unschedulable nodes are never scheduled or deleted
+	ownerAnnotation = "kubescaler/owner"
+	ownerName       = "kubescaler"
+
+	// nodes with these annotations (or labels) set to "true" are never deleted or never marked as unschedulable
+	scaleDownDisabledAnnotation = "kubescaler/scale-down-disabled"
+	cordonDisabledAnnotation    = "kubescaler/cordon-disabled"
+
+	// nodes are neither marked as unschedulable nor deleted before this RFC 3339 time
+	protectedUntilAnnotation = "kubescaler/protected-until"
+
+	// costAnnotation (or label) is the node cost used by the cost scale down strategy, e.g. the hourly price
+	costAnnotation = "kubescaler/cost"
+
+	zoneLabel = "topology.kubernetes.io/zone"
+
+	// nodeRegistrationDuration is the duration after the node creation in which its first ready condition is reported, a
+	// later not ready transition means the node was ready before
+	nodeRegistrationDuration = time.Minute
 )
 
 type NodeList struct {
@@ -16,6 +37,16 @@ type NodeList struct {
 type Node struct {
 	N    *v1.Node
 	Pods []v1.Pod
+
+	// DrainTaintKey is the NoSchedule taint used to mark the node as unschedulable instead of cordoning it
+	DrainTaintKey string
+
+	// InterruptionTaints and InterruptionConditions are the taint keys and the true condition types signaling the node termination
+	InterruptionTaints     []string
+	InterruptionConditions []string
+
+	// WarmUpTaintKey is the taint set on the new nodes until the images are pulled
+	WarmUpTaintKey string
 }
 
 type Resource struct {
@@ -23,7 +54,18 @@ type Resource struct {
 	Value int64
 }
 
+// AvailableSlot returns the sum of the slots fitting on each available node, the partial slots left on the nodes are not counted
 func (n *NodeList) AvailableSlot(need Resource) int64 {
+	var a int64
+	for _, node := range n.AvailableNodes() {
+		a += node.AvailableSlot(need)
+	}
+	return a
+}
+
+// AggregateAvailableSlot returns the slots fitting in the total available resource of the nodes, it's greater than
+// AvailableSlot if the available resource is fragmented across the nodes
+func (n *NodeList) AggregateAvailableSlot(need Resource) int64 {
 	ar := n.AvailableResource(need.Name)
 	return ar / need.Value
 }
@@ -39,17 +81,63 @@ func (n *NodeList) AvailableResource(resource v1.ResourceName) int64 {
 func (n *NodeList) AvailableNodes() []*Node {
 	var nodes []*Node
 	for _, node := range n.Nodes {
-		if node.IsReady() && node.IsSchedulable() {
+		if node.IsAvailable() {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// SetDrainTaintKey sets the drain taint key of all nodes
+func (n *NodeList) SetDrainTaintKey(key string) {
+	for _, node := range n.Nodes {
+		node.DrainTaintKey = key
+	}
+}
+
+// SetInterruptionSignals sets the interruption taint keys and condition types of all nodes
+func (n *NodeList) SetInterruptionSignals(taints, conditions []string) {
+	for _, node := range n.Nodes {
+		node.InterruptionTaints = taints
+		node.InterruptionConditions = conditions
+	}
+}
+
+// SetWarmUpTaintKey sets the warm-up taint key of all nodes
+func (n *NodeList) SetWarmUpTaintKey(key string) {
+	for _, node := range n.Nodes {
+		node.WarmUpTaintKey = key
+	}
+}
+
+// ProvisioningNodes returns the schedulable nodes which are booting in the boot timeout or warming up, they're members of
+// the node pool which will be available soon
+func (n *NodeList) ProvisioningNodes(now time.Time, bootTimeout time.Duration) []*Node {
+	var nodes []*Node
+	for _, node := range n.Nodes {
+		if node.IsSchedulable() && !node.IsInterrupted() && (node.IsBooting(now, bootTimeout) || node.IsWarmingUp()) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// InterruptedNodes returns the nodes signaled to terminate
+func (n *NodeList) InterruptedNodes() []*Node {
+	var nodes []*Node
+	for _, node := range n.Nodes {
+		if node.IsInterrupted() {
 			nodes = append(nodes, node)
 		}
 	}
 	return nodes
 }
 
+// UnschedulableNodes returns the nodes marked as unschedulable by the scaler
 func (n *NodeList) UnschedulableNodes() []*Node {
 	var nodes []*Node
 	for _, node := range n.Nodes {
-		if !node.IsSchedulable() {
+		if node.IsMarkedAsUnschedulable() {
 			nodes = append(nodes, node)
 		}
 	}
@@ -70,24 +158,52 @@ func (n *Node) AvailableResource(resource v1.ResourceName) int64 {
 	return n.ResourceCapacity(resource) - n.UsingResources(resource)
 }
 
+// AvailableSlot returns the slots fitting in the available resource of the node
+func (n *Node) AvailableSlot(need Resource) int64 {
+	ar := n.AvailableResource(need.Name)
+	if ar <= 0 {
+		return 0
+	}
+	return ar / need.Value
+}
+
 func (n *Node) MarkAsSchedulable() error {
-	n.N.Spec.Unschedulable = false
+	if n.DrainTaintKey != "" {
+		n.removeDrainTaint()
+	} else {
+		n.N.Spec.Unschedulable = false
+	}
+
 	t, err := time.Now().UTC().MarshalText()
 	if err != nil {
 		return err
 	}
+
+	if n.N.ObjectMeta.Annotations == nil {
+		n.N.ObjectMeta.Annotations = make(map[string]string)
+	}
 	n.N.ObjectMeta.Annotations[timestampAnnotation] = string(t)
+	delete(n.N.ObjectMeta.Annotations, ownerAnnotation)
 	return nil
 }
 
 func (n *Node) MarkAsUnschedulable() error {
-	n.N.Spec.Unschedulable = true
+	if n.DrainTaintKey != "" {
+		n.addDrainTaint()
+	} else {
+		n.N.Spec.Unschedulable = true
+	}
+
 	t, err := time.Now().UTC().MarshalText()
 	if err != nil {
 		return err
 	}
 
+	if n.N.ObjectMeta.Annotations == nil {
+		n.N.ObjectMeta.Annotations = make(map[string]string)
+	}
 	n.N.ObjectMeta.Annotations[timestampAnnotation] = string(t)
+	n.N.ObjectMeta.Annotations[ownerAnnotation] = ownerName
 	return nil
 }
 
@@ -100,6 +216,55 @@ func (n *Node) SchedulingMarkTimestamp() (time.Time, error) {
 	return t, nil
 }
 
+func (n *Node) IsScaleDownDisabled() bool {
+	return n.isEnabled(scaleDownDisabledAnnotation)
+}
+
+func (n *Node) IsCordonDisabled() bool {
+	return n.isEnabled(cordonDisabledAnnotation)
+}
+
+// IsProtected reports whether the node is protected at the given time, an invalid protection time protects the node
+func (n *Node) IsProtected(now time.Time) bool {
+	v, ok := n.N.ObjectMeta.Annotations[protectedUntilAnnotation]
+	if !ok {
+		return false
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return true
+	}
+	return now.Before(t)
+}
+
+// Zone returns the topology zone label of the node
+func (n *Node) Zone() string {
+	return n.N.ObjectMeta.Labels[zoneLabel]
+}
+
+// Cost returns the node cost annotation (or label), zero if not set or invalid
+func (n *Node) Cost() float64 {
+	v, ok := n.N.ObjectMeta.Annotations[costAnnotation]
+	if !ok {
+		v = n.N.ObjectMeta.Labels[costAnnotation]
+	}
+
+	c, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0
+	}
+	return c
+}
+
+func (n *Node) isEnabled(key string) bool {
+	v, ok := n.N.ObjectMeta.Annotations[key]
+	if !ok {
+		v, ok = n.N.ObjectMeta.Labels[key]
+	}
+	return ok && v == "true"
+}
+
 func (n *Node) IsReady() bool {
 	for _, c := range n.N.Status.Conditions {
 		if c.Type == v1.NodeReady && c.Status == v1.ConditionTrue {
@@ -109,8 +274,124 @@ func (n *Node) IsReady() bool {
 	return false
 }
 
+// NotReadySince returns the last ready condition transition time, or the creation time if the node has no ready condition
+func (n *Node) NotReadySince() time.Time {
+	for _, c := range n.N.Status.Conditions {
+		if c.Type == v1.NodeReady && !c.LastTransitionTime.IsZero() {
+			return c.LastTransitionTime.Time
+		}
+	}
+	return n.N.CreationTimestamp.Time
+}
+
+// IsBooting reports whether the node has not been ready since it joined the cluster and was created in the timeout, the
+// nodes which became not ready after being ready are not booting
+func (n *Node) IsBooting(now time.Time, timeout time.Duration) bool {
+	created := n.N.CreationTimestamp.Time
+	return !n.IsReady() && now.Sub(created) < timeout && n.NotReadySince().Sub(created) < nodeRegistrationDuration
+}
+
+// IsAvailable reports whether the node is ready, schedulable, warmed up and not interrupted
+func (n *Node) IsAvailable() bool {
+	return n.IsReady() && n.IsSchedulable() && !n.IsWarmingUp() && !n.IsInterrupted()
+}
+
+// IsWarmingUp reports whether the node has the warm-up taint
+func (n *Node) IsWarmingUp() bool {
+	if n.WarmUpTaintKey == "" {
+		return false
+	}
+
+	for _, t := range n.N.Spec.Taints {
+		if t.Key == n.WarmUpTaintKey {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInterrupted reports whether the node has an interruption taint or condition, e.g. a spot instance termination notice
+func (n *Node) IsInterrupted() bool {
+	for _, t := range n.N.Spec.Taints {
+		for _, key := range n.InterruptionTaints {
+			if t.Key == key {
+				return true
+			}
+		}
+	}
+
+	for _, c := range n.N.Status.Conditions {
+		for _, condition := range n.InterruptionConditions {
+			if string(c.Type) == condition && c.Status == v1.ConditionTrue {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsSchedulable reports whether new pods can be scheduled on the node, it's false for the cordoned and drain tainted nodes
 func (n *Node) IsSchedulable() bool {
-	return !n.N.Spec.Unschedulable
+	return !n.N.Spec.Unschedulable && !n.hasDrainTaint()
+}
+
+// IsMarkedAsUnschedulable reports whether the node is marked as unschedulable by the scaler using the drain taint, or by cordoning if there is no drain taint key
+func (n *Node) IsMarkedAsUnschedulable() bool {
+	if n.N.ObjectMeta.Annotations[ownerAnnotation] != ownerName {
+		return false
+	}
+
+	if n.DrainTaintKey != "" {
+		return n.hasDrainTaint()
+	}
+	return n.N.Spec.Unschedulable
+}
+
+func (n *Node) hasDrainTaint() bool {
+	if n.DrainTaintKey == "" {
+		return false
+	}
+
+	for _, t := range n.N.Spec.Taints {
+		if t.Key == n.DrainTaintKey && t.Effect == v1.TaintEffectNoSchedule {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Node) addDrainTaint() {
+	if n.hasDrainTaint() {
+		return
+	}
+
+	n.N.Spec.Taints = append(n.N.Spec.Taints, v1.Taint{
+		Key:    n.DrainTaintKey,
+		Value:  "true",
+		Effect: v1.TaintEffectNoSchedule,
+	})
+}
+
+func (n *Node) removeDrainTaint() {
+	var taints []v1.Taint
+	for _, t := range n.N.Spec.Taints {
+		if t.Key == n.DrainTaintKey && t.Effect == v1.TaintEffectNoSchedule {
+			continue
+		}
+		taints = append(taints, t)
+	}
+	n.N.Spec.Taints = taints
+}
+
+func (n *Node) removeWarmUpTaint() {
+	var taints []v1.Taint
+	for _, t := range n.N.Spec.Taints {
+		if t.Key == n.WarmUpTaintKey {
+			continue
+		}
+		taints = append(taints, t)
+	}
+	n.N.Spec.Taints = taints
 }
 
 func (n *Node) ResourceCapacity(resource v1.ResourceName) int64 {
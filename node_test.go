@@ -0,0 +1,66 @@
+package kubescaler
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestNodeList_AvailableSlot(t *testing.T) {
+	tests := []struct {
+		name                   string
+		nodes                  int
+		podsPerNode            int
+		podCPU                 string
+		slotCPU                int64
+		expectedSlot           int64
+		expectedAggregatedSlot int64
+	}{
+		{
+			name:                   "no_fragmentation",
+			nodes:                  2,
+			podsPerNode:            6,
+			podCPU:                 "0.1",
+			slotCPU:                200,
+			expectedSlot:           4,
+			expectedAggregatedSlot: 4,
+		},
+		{
+			name:                   "fragmented",
+			nodes:                  10,
+			podsPerNode:            1,
+			podCPU:                 "0.5",
+			slotCPU:                600,
+			expectedSlot:           0,
+			expectedAggregatedSlot: 8,
+		},
+		{
+			name:                   "over_committed",
+			nodes:                  2,
+			podsPerNode:            3,
+			podCPU:                 "0.4",
+			slotCPU:                100,
+			expectedSlot:           0,
+			expectedAggregatedSlot: -4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := &NodeList{}
+			nodes := newNodeList(tt.nodes, false)
+			for i := range nodes.Items {
+				list.Nodes = append(list.Nodes, &Node{
+					N:    &nodes.Items[i],
+					Pods: newPodList(repeatRequestPod(tt.podsPerNode, requestPod{cpuResource: tt.podCPU})).Items,
+				})
+			}
+
+			need := Resource{Name: v1.ResourceCPU, Value: tt.slotCPU}
+			slot, aggregated := list.AvailableSlot(need), list.AggregateAvailableSlot(need)
+			if slot != tt.expectedSlot || aggregated != tt.expectedAggregatedSlot {
+				t.Logf("expected %d slots and %d aggregated slots, got %d and %d", tt.expectedSlot, tt.expectedAggregatedSlot, slot, aggregated)
+				t.FailNow()
+			}
+		})
+	}
+}
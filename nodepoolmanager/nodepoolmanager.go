@@ -3,6 +3,7 @@ package nodepoolmanager
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 var (
@@ -16,6 +17,23 @@ type Provider interface {
 	DeleteNodes(ctx context.Context, IDs []string) error
 }
 
+// Node is a node pool member as seen by the cloud provider, it may not be registered in the cluster yet
+type Node struct {
+	Name      string
+	State     string
+	CreatedAt time.Time
+}
+
+// NodeLister is implemented by the providers which can list the node pool members
+type NodeLister interface {
+	Nodes(ctx context.Context) ([]Node, error)
+}
+
+// ZonalProvider is implemented by the providers managing the node pool nodes per zone
+type ZonalProvider interface {
+	ResizeZone(ctx context.Context, zone string, count int) error
+}
+
 type Driver interface {
 	Connect(config interface{}) (Provider, error)
 }
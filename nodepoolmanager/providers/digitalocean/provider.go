@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/digitalocean/godo"
+	"github.com/theredrad/kubescaler/nodepoolmanager"
 )
 
 var (
@@ -76,14 +77,50 @@ func (p *Provider) ResizeNode(ctx context.Context, count int) error {
 	return err
 }
 
+func (p *Provider) Nodes(ctx context.Context) ([]nodepoolmanager.Node, error) {
+	np, _, err := p.client.Kubernetes.GetNodePool(ctx, p.clusterID, p.nodePoolID)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []nodepoolmanager.Node
+	for _, node := range np.Nodes {
+		n := nodepoolmanager.Node{
+			Name:      node.Name,
+			CreatedAt: node.CreatedAt,
+		}
+		if node.Status != nil {
+			n.State = node.Status.State
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
 func (p *Provider) DeleteNodes(ctx context.Context, IDs []string) error {
+	if len(IDs) == 0 {
+		return nil
+	}
+
+	np, _, err := p.client.Kubernetes.GetNodePool(ctx, p.clusterID, p.nodePoolID)
+	if err != nil {
+		return err
+	}
+
+	nodes := make(map[string]string)
+	for _, node := range np.Nodes {
+		nodes[node.Name] = node.ID
+	}
+
 	for _, ID := range IDs {
-		np, _, err := p.client.Kubernetes.GetNodePool(ctx, p.clusterID, p.nodePoolID)
-		for _, node := range np.Nodes {
-			if node.Name == ID {
-				_, err = p.client.Kubernetes.DeleteNode(ctx, p.clusterID, p.nodePoolID, node.ID, &godo.KubernetesNodeDeleteRequest{})
-				return err
-			}
+		nodeID, ok := nodes[ID]
+		if !ok {
+			continue
+		}
+
+		_, err = p.client.Kubernetes.DeleteNode(ctx, p.clusterID, p.nodePoolID, nodeID, &godo.KubernetesNodeDeleteRequest{})
+		if err != nil {
+			return err
 		}
 	}
 	return nil
@@ -0,0 +1,166 @@
+package kubescaler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// nodeDrainingAnnotation is set on the dedicated server pods while their node is marked as unschedulable, the value
+	// is the RFC3339 time the pods are signaled at
+	nodeDrainingAnnotation = "kubescaler/node-draining"
+
+	// drainNotifyTimeout is the time limit of the notification requests of a scale pass
+	drainNotifyTimeout = 5 * time.Second
+)
+
+// DrainNotification is the body posted to the dedicated server pods endpoint when their node draining state changes
+type DrainNotification struct {
+	Node     string `json:"node"`
+	Draining bool   `json:"draining"`
+}
+
+// drainNotice is a draining state notification of a dedicated server pod
+type drainNotice struct {
+	pod          *v1.Pod
+	notification DrainNotification
+}
+
+// notifyDrainingServers signals the draining state changes of the nodes to their dedicated servers after the scale pass,
+// so marking the nodes doesn't wait for the notifications. The servers which failed to be signaled are signaled again in
+// the next passes, the notification is best effort and the failures are only logged
+func (s *Scaler) notifyDrainingServers() error {
+	if !s.config.NotifyDraining && s.config.DrainNotifyPort <= 0 {
+		return nil
+	}
+
+	nodes, err := s.nodes()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	exists := make(map[string]bool)
+	var notices []drainNotice
+	for _, n := range nodes.Nodes {
+		draining := n.IsMarkedAsUnschedulable()
+		pods := s.filterPods(n.Pods)
+		for i := range pods {
+			pod := &pods[i]
+			key := pod.Namespace + "/" + pod.Name
+			exists[key] = true
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+
+			s.annotateServer(n, pod, draining, now)
+			// the schedulable node servers are signaled only if they were signaled as draining
+			if s.config.DrainNotifyPort > 0 && draining != s.notified[key] {
+				notices = append(notices, drainNotice{pod: pod, notification: DrainNotification{Node: n.N.Name, Draining: draining}})
+			}
+		}
+	}
+
+	for _, notice := range s.postDrainNotifications(notices) {
+		if notice.notification.Draining {
+			s.notified[notice.pod.Namespace+"/"+notice.pod.Name] = true
+		} else {
+			delete(s.notified, notice.pod.Namespace+"/"+notice.pod.Name)
+		}
+	}
+
+	for key := range s.notified {
+		if !exists[key] {
+			delete(s.notified, key)
+		}
+	}
+	return nil
+}
+
+// annotateServer sets the draining annotation on the pod of a draining node and removes it otherwise
+func (s *Scaler) annotateServer(n *Node, pod *v1.Pod, draining bool, now time.Time) {
+	if !s.config.NotifyDraining {
+		return
+	}
+
+	_, annotated := pod.Annotations[nodeDrainingAnnotation]
+	var err error
+	if draining && !annotated {
+		err = s.k8s.AnnotatePod(context.Background(), pod, nodeDrainingAnnotation, now.Format(time.RFC3339))
+	} else if !draining && annotated {
+		err = s.k8s.RemovePodAnnotation(context.Background(), pod, nodeDrainingAnnotation)
+	}
+	if err != nil {
+		s.config.Logger.Errorf("error while annotating pod %s/%s of node %s: %s", pod.Namespace, pod.Name, n.N.Name, err)
+	}
+}
+
+// postDrainNotifications posts the notifications in parallel within the notification time limit, it returns the sent
+// notifications
+func (s *Scaler) postDrainNotifications(notices []drainNotice) []drainNotice {
+	if len(notices) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainNotifyTimeout)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sent []drainNotice
+	)
+	for _, notice := range notices {
+		wg.Add(1)
+		go func(notice drainNotice) {
+			defer wg.Done()
+
+			err := s.postDrainNotification(ctx, notice.pod, notice.notification)
+			if err != nil {
+				s.config.Logger.Errorf("error while notifying pod %s/%s of node %s: %s", notice.pod.Namespace, notice.pod.Name, notice.notification.Node, err)
+				return
+			}
+
+			mu.Lock()
+			sent = append(sent, notice)
+			mu.Unlock()
+		}(notice)
+	}
+	wg.Wait()
+	return sent
+}
+
+func (s *Scaler) postDrainNotification(ctx context.Context, pod *v1.Pod, notification DrainNotification) error {
+	if pod.Status.PodIP == "" {
+		return fmt.Errorf("pod has no IP")
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, s.config.DrainNotifyPort, s.config.DrainNotifyPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package kubescaler
+
+import (
+	"encoding/json"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sT "k8s.io/client-go/testing"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestScaler_notifyServers(t *testing.T) {
+	var (
+		mu            sync.Mutex
+		requests      int
+		notifications []DrainNotification
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// the first request fails to be retried in the next pass
+		requests++
+		if requests == 1 || r.Method != http.MethodPost || r.URL.Path != "/drain" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var n DrainNotification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		notifications = append(notifications, n)
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Logf("expected server address, got err: %s", err)
+		t.FailNow()
+	}
+	notifyPort, _ := strconv.Atoi(port)
+
+	nodes := newNodeList(1, false)
+	pods := newPodList(append(
+		repeatRequestPod(2, requestPod{cpuResource: "0.1", isDedicatedServer: true}),
+		requestPod{cpuResource: "0.1"},
+	))
+	for i := range pods.Items {
+		pods.Items[i].Status.PodIP = host
+	}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): pods,
+	})
+
+	var patches int
+	annotations := make(map[string]interface{})
+	clientSet.AddReactor("patch", "pods", func(a k8sT.Action) (bool, runtime.Object, error) {
+		pa := a.(k8sT.PatchAction)
+		var patch struct {
+			Metadata struct {
+				Annotations map[string]interface{} `json:"annotations"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(pa.GetPatch(), &patch); err != nil {
+			return true, nil, err
+		}
+		annotations[pa.GetName()] = patch.Metadata.Annotations[nodeDrainingAnnotation]
+		patches++
+		return true, nil, nil
+	})
+
+	srv := NewScaler(nil, NewK8S(clientSet), &Config{
+		NodeSelector:    nodeSelector,
+		PodCPURequest:   100,
+		PodLabelName:    podLabelName,
+		PodLabelValue:   podLabelValue,
+		NotifyDraining:  true,
+		DrainNotifyPort: notifyPort,
+		DrainNotifyPath: "/drain",
+	})
+
+	list, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	err = srv.markNodeAsUnschedulable(list.Nodes[0])
+	if err != nil {
+		t.Logf("expected node to be marked, got err: %s", err)
+		t.FailNow()
+	}
+
+	// the servers are signaled after the scale pass
+	if len(annotations) != 0 || requests != 0 {
+		t.Logf("expected no notification while marking the node, got %d annotations and %d requests", len(annotations), requests)
+		t.FailNow()
+	}
+
+	err = srv.notifyDrainingServers()
+	if err != nil {
+		t.Logf("expected notifications, got err: %s", err)
+		t.FailNow()
+	}
+
+	if len(annotations) != 2 {
+		t.Logf("expected 2 dedicated server pods to be annotated, got %d", len(annotations))
+		t.FailNow()
+	}
+	for name, v := range annotations {
+		if v == nil {
+			t.Logf("expected pod %s to be annotated as draining", name)
+			t.FailNow()
+		}
+	}
+
+	if len(notifications) != 1 {
+		t.Logf("expected 1 draining notification and 1 failure, got %+v", notifications)
+		t.FailNow()
+	}
+
+	// the failed notification is retried and the missing annotations are set again, the notified pods are skipped
+	for i := 0; i < 2; i++ {
+		err = srv.notifyDrainingServers()
+		if err != nil {
+			t.Logf("expected notifications, got err: %s", err)
+			t.FailNow()
+		}
+	}
+
+	if len(notifications) != 2 || !notifications[1].Draining || notifications[1].Node != fmt.Sprintf(fmtNodeName, "0") {
+		t.Logf("expected 2 draining notifications, got %+v", notifications)
+		t.FailNow()
+	}
+
+	if patches != 6 {
+		t.Logf("expected the missing annotations to be set in every pass, got %d patches", patches)
+		t.FailNow()
+	}
+
+	for i := range pods.Items {
+		pods.Items[i].Annotations = map[string]string{nodeDrainingAnnotation: "true"}
+	}
+	list, err = srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	err = srv.markNodeAsSchedulable(list.Nodes[0])
+	if err != nil {
+		t.Logf("expected node to be marked, got err: %s", err)
+		t.FailNow()
+	}
+
+	// only the servers signaled as draining are signaled, once
+	for i := 0; i < 2; i++ {
+		err = srv.notifyDrainingServers()
+		if err != nil {
+			t.Logf("expected notifications, got err: %s", err)
+			t.FailNow()
+		}
+	}
+
+	for name, v := range annotations {
+		if v != nil {
+			t.Logf("expected the draining annotation of pod %s to be removed", name)
+			t.FailNow()
+		}
+	}
+
+	if len(notifications) != 4 || notifications[2].Draining || notifications[3].Draining || len(srv.notified) != 0 {
+		t.Logf("expected 2 schedulable notifications, got %+v", notifications)
+		t.FailNow()
+	}
+}
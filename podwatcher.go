@@ -12,8 +12,8 @@ type PodWatcher struct {
 }
 
 func (pw *PodWatcher) Watch() {
+	pw.wg.Add(1)
 	go func() {
-		pw.wg.Add(1)
 		for e := range pw.w.ResultChan() {
 			pw.Events <- e
 		}
@@ -0,0 +1,49 @@
+package kubescaler
+
+import (
+	"time"
+)
+
+// recycleOldNodes marks the oldest node exceeding MaxNodeAge as unschedulable, so it's deleted once its servers are finished
+// and replaced by a new node, a node is recycled at a time
+func (s *Scaler) recycleOldNodes(nodes *NodeList) error {
+	if s.config.MaxNodeAge <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, n := range nodes.UnschedulableNodes() {
+		if s.isExpiredNode(n, now) {
+			s.config.Logger.Debugf("node %s is being recycled", n.N.Name)
+			return nil
+		}
+	}
+
+	var expired []*Node
+	for _, n := range s.unschedulingCandidates(nodes.AvailableNodes()) {
+		if s.isExpiredNode(n, now) {
+			expired = append(expired, n)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	OldestNodeStrategy{}.Sort(expired, nil)
+
+	n := expired[0]
+	err := s.markNodeAsUnschedulable(n)
+	if err != nil {
+		return err
+	}
+	s.config.Logger.Infof("node %s is older than %s, marked as unschedulable to recycle", n.N.Name, s.config.MaxNodeAge)
+	return nil
+}
+
+// isExpiredNode reports whether the node is older than MaxNodeAge
+func (s *Scaler) isExpiredNode(n *Node, now time.Time) bool {
+	if s.config.MaxNodeAge <= 0 || n.N.CreationTimestamp.IsZero() {
+		return false
+	}
+	return now.Sub(n.N.CreationTimestamp.Time) > s.config.MaxNodeAge
+}
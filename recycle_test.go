@@ -0,0 +1,106 @@
+package kubescaler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/theredrad/kubescaler/mocks"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sT "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func TestScaler_recycleOldNodes(t *testing.T) {
+	now := time.Now()
+	nodes := newNodeList(3, false)
+	for i, age := range []time.Duration{30 * time.Hour, 48 * time.Hour, time.Hour} {
+		nodes.Items[i].CreationTimestamp = metav1.NewTime(now.Add(-age))
+	}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(2, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	srv := NewScaler(nil, NewK8S(clientSet), &Config{
+		NodeSelector:  nodeSelector,
+		PodCPURequest: 100,
+		PodLabelName:  podLabelName,
+		PodLabelValue: podLabelValue,
+		MaxNodeAge:    24 * time.Hour,
+	})
+
+	// the second pass must wait for the first recycled node
+	for i := 0; i < 2; i++ {
+		list, err := srv.nodes()
+		if err != nil {
+			t.Logf("expected node list, got err: %s", err)
+			t.FailNow()
+		}
+
+		err = srv.recycleOldNodes(list)
+		if err != nil {
+			t.Logf("expected recycling, got err: %s", err)
+			t.FailNow()
+		}
+	}
+
+	list, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	unschedulable := list.UnschedulableNodes()
+	if len(unschedulable) != 1 || unschedulable[0].N.Name != fmt.Sprintf(fmtNodeName, "1") {
+		t.Logf("expected only the oldest node to be recycled, got %d nodes", len(unschedulable))
+		t.FailNow()
+	}
+
+	err = srv.checkForScheduling(list, &Resource{
+		Name:  v1.ResourceCPU,
+		Value: 1000,
+	})
+	if !errors.Is(err, ErrNotEnoughResources) {
+		t.Logf("expected the recycled node not to be scheduled, got err: %v", err)
+		t.FailNow()
+	}
+}
+
+func TestScaler_scaleRecycleError(t *testing.T) {
+	now := time.Now()
+	nodes := newNodeList(2, false)
+	for i, age := range []time.Duration{48 * time.Hour, time.Hour} {
+		nodes.Items[i].CreationTimestamp = metav1.NewTime(now.Add(-age))
+	}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(10, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(10, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	// the old node can't be marked as unschedulable
+	clientSet.PrependReactor("update", "nodes", func(a k8sT.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("update failed")
+	})
+
+	ctrl := gomock.NewController(t)
+	npm := mocks.NewMockNodePoolProvider(ctrl)
+	// the failed recycle doesn't skip the scale up
+	npm.EXPECT().ResizeNode(gomock.Any(), 3).Return(nil).Times(1)
+
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:   nodeSelector,
+		MinimumNode:    1,
+		MaximumNode:    5,
+		PodCPURequest:  100,
+		BufferSlotSize: 4,
+		PodLabelName:   podLabelName,
+		PodLabelValue:  podLabelValue,
+		MaxNodeAge:     24 * time.Hour,
+	})
+
+	err := srv.scale()
+	if err != nil {
+		t.Logf("expected scale, got err: %s", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,169 @@
+package kubescaler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrReservationTooLong = errors.New("reservation ttl exceeds the maximum ttl")
+	ErrReservationLimit   = errors.New("reserved slots exceed the maximum reserved slots")
+)
+
+// Reservation holds slots on top of the buffer until it expires or is released
+type Reservation struct {
+	ID        string    `json:"id"`
+	Slots     int64     `json:"slots"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Reservations is the store of the capacity reservations, it's safe for concurrent use
+type Reservations struct {
+	mu           sync.Mutex
+	reservations map[string]Reservation
+
+	// maxSlots caps the total reserved slots and maxTTL the reservation duration (zero is unlimited)
+	maxSlots int64
+	maxTTL   time.Duration
+}
+
+func NewReservations(maxSlots int64, maxTTL time.Duration) *Reservations {
+	return &Reservations{
+		reservations: make(map[string]Reservation),
+		maxSlots:     maxSlots,
+		maxTTL:       maxTTL,
+	}
+}
+
+// Reserve adds a reservation of the slots until the expiry time, it fails if the reservation is longer than the maximum
+// ttl or the total reserved slots would exceed the maximum reserved slots
+func (r *Reservations) Reserve(slots int64, expiresAt time.Time) (Reservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.maxTTL > 0 && expiresAt.Sub(now) > r.maxTTL {
+		return Reservation{}, ErrReservationTooLong
+	}
+
+	if r.maxSlots > 0 {
+		reserved := slots
+		for _, res := range r.reservations {
+			if now.Before(res.ExpiresAt) {
+				reserved += res.Slots
+			}
+		}
+		if reserved > r.maxSlots {
+			return Reservation{}, ErrReservationLimit
+		}
+	}
+
+	res := Reservation{
+		ID:        uuid.New().String(),
+		Slots:     slots,
+		ExpiresAt: expiresAt,
+	}
+	r.reservations[res.ID] = res
+	return res, nil
+}
+
+// Release removes the reservation, it reports whether the reservation existed
+func (r *Reservations) Release(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.reservations[id]
+	delete(r.reservations, id)
+	return ok
+}
+
+// Active returns the reservations which are not expired at the given time ordered by expiry, the expired ones are dropped
+func (r *Reservations) Active(now time.Time) []Reservation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var active []Reservation
+	for id, res := range r.reservations {
+		if !now.Before(res.ExpiresAt) {
+			delete(r.reservations, id)
+			continue
+		}
+		active = append(active, res)
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].ExpiresAt.Before(active[j].ExpiresAt)
+	})
+	return active
+}
+
+// Slots returns the reserved slots at the given time
+func (r *Reservations) Slots(now time.Time) int64 {
+	var slots int64
+	for _, res := range r.Active(now) {
+		slots += res.Slots
+	}
+	return slots
+}
+
+type reservationRequest struct {
+	Slots      int64 `json:"slots"`
+	TTLSeconds int64 `json:"ttlSeconds"`
+}
+
+// NewReservationHandler serves the reservation API, the requests must have the "Authorization: Bearer <token>" header if
+// the token is not empty:
+//
+//	POST /reservations with {"slots": 200, "ttlSeconds": 1800} reserves the slots
+//	GET /reservations lists the active reservations
+//	DELETE /reservations/{id} releases the reservation
+func NewReservationHandler(r *Reservations, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/reservations"), "/")
+		switch {
+		case req.Method == http.MethodGet && id == "":
+			writeJSON(w, http.StatusOK, r.Active(time.Now()))
+		case req.Method == http.MethodPost && id == "":
+			var rr reservationRequest
+			if err := json.NewDecoder(req.Body).Decode(&rr); err != nil || rr.Slots <= 0 || rr.TTLSeconds <= 0 {
+				http.Error(w, "slots and ttlSeconds must be positive", http.StatusBadRequest)
+				return
+			}
+			res, err := r.Reserve(rr.Slots, time.Now().Add(time.Duration(rr.TTLSeconds)*time.Second))
+			switch {
+			case errors.Is(err, ErrReservationTooLong):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, ErrReservationLimit):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				writeJSON(w, http.StatusCreated, res)
+			}
+		case req.Method == http.MethodDelete && id != "":
+			if !r.Release(id) {
+				http.Error(w, "reservation not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
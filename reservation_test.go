@@ -0,0 +1,104 @@
+package kubescaler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReservations_Slots(t *testing.T) {
+	now := time.Now()
+	r := NewReservations(300, 2*time.Hour)
+	r.Reserve(200, now.Add(30*time.Minute))
+	res, _ := r.Reserve(50, now.Add(time.Hour))
+	r.Reserve(10, now.Add(-time.Minute))
+
+	if _, err := r.Reserve(100, now.Add(time.Hour)); !errors.Is(err, ErrReservationLimit) {
+		t.Logf("expected the reservation to exceed the maximum slots, got err: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := r.Reserve(10, now.Add(3*time.Hour)); !errors.Is(err, ErrReservationTooLong) {
+		t.Logf("expected the reservation to exceed the maximum ttl, got err: %v", err)
+		t.FailNow()
+	}
+
+	if slots := r.Slots(now); slots != 250 {
+		t.Logf("expected 250 reserved slots, got %d", slots)
+		t.FailNow()
+	}
+
+	if !r.Release(res.ID) || r.Release(res.ID) {
+		t.Logf("expected the reservation to be released once")
+		t.FailNow()
+	}
+
+	if slots := r.Slots(now.Add(45 * time.Minute)); slots != 0 {
+		t.Logf("expected the reservations to expire, got %d reserved slots", slots)
+		t.FailNow()
+	}
+}
+
+func TestNewReservationHandler(t *testing.T) {
+	r := NewReservations(1000, time.Hour)
+	handler := NewReservationHandler(r, "secret")
+	request := func(method, target, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reservations", strings.NewReader(`{"slots": 200, "ttlSeconds": 600}`)))
+	if w.Code != http.StatusUnauthorized {
+		t.Logf("expected status %d without token, got %d", http.StatusUnauthorized, w.Code)
+		t.FailNow()
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodPost, "/reservations", `{"slots": 200, "ttlSeconds": 600}`))
+	if w.Code != http.StatusCreated {
+		t.Logf("expected status %d, got %d", http.StatusCreated, w.Code)
+		t.FailNow()
+	}
+
+	var res Reservation
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil || res.Slots != 200 {
+		t.Logf("expected a reservation of 200 slots, got %+v with err: %v", res, err)
+		t.FailNow()
+	}
+
+	srv := NewScaler(nil, nil, &Config{
+		BufferSlotSize: 4,
+		Reservations:   r,
+	})
+	if size := srv.bufferSize(0); size != 204 {
+		t.Logf("expected buffer size 204, got %d", size)
+		t.FailNow()
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodPost, "/reservations", `{"slots": -1}`))
+	if w.Code != http.StatusBadRequest {
+		t.Logf("expected status %d for invalid reservation, got %d", http.StatusBadRequest, w.Code)
+		t.FailNow()
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodPost, "/reservations", `{"slots": 1000, "ttlSeconds": 600}`))
+	if w.Code != http.StatusConflict {
+		t.Logf("expected status %d for exceeding reservation, got %d", http.StatusConflict, w.Code)
+		t.FailNow()
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodDelete, "/reservations/"+res.ID, ""))
+	if w.Code != http.StatusNoContent || r.Slots(time.Now()) != 0 {
+		t.Logf("expected the reservation to be released, got status %d", w.Code)
+		t.FailNow()
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"github.com/theredrad/kubescaler/nodepoolmanager"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"math"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -27,20 +30,166 @@ type Config struct {
 
 	EmptyNodeExpiration time.Duration
 
+	// DrainTimeout enables evicting the pods which are not dedicated servers before deleting a node, the node is deleted once
+	// drained. A node which is not drained in this duration is skipped for this duration before the drain is retried
+	DrainTimeout time.Duration
+	// DrainGracePeriod overrides the pods termination grace period on eviction (zero uses the pod grace period)
+	DrainGracePeriod time.Duration
+
+	// MaxDrainDuration is the deadline of the dedicated servers on the nodes marked as unschedulable, the remaining servers
+	// are annotated with the session deadline and deleted gracefully after SessionDeadlineGracePeriod (zero disables)
+	MaxDrainDuration           time.Duration
+	SessionDeadlineGracePeriod time.Duration
+
+	// NotifyDraining annotates the dedicated server pods with kubescaler/node-draining while their node is marked as
+	// unschedulable, so the servers can stop accepting new sessions
+	NotifyDraining bool
+	// DrainNotifyPort and DrainNotifyPath are the dedicated server pods endpoint receiving a POST request with the node
+	// draining state when their node is marked as unschedulable or schedulable again (zero port disables)
+	DrainNotifyPort int
+	DrainNotifyPath string
+
+	// WarmUpTaintKey is the taint set on the new nodes by the node pool, it's removed once the pod matching WarmUpPodSelector
+	// (e.g. the image pre-pull DaemonSet) is ready on the node or after WarmUpTimeout, the nodes are counted as available
+	// after it (empty disables, zero timeout waits for the pod)
+	WarmUpTaintKey    string
+	WarmUpPodSelector string
+	WarmUpTimeout     time.Duration
+
+	// DrainTaintKey marks the nodes as unschedulable using a NoSchedule taint instead of cordoning (leave empty to cordon)
+	DrainTaintKey string
+
 	BufferSlotSize int64
+	// BufferMode selects how the buffer size is computed, BufferSlotSize is used as the fixed buffer and the step mode default
+	BufferMode BufferMode
+	// BufferPercent is the buffer size in percent of the used slots in percent mode
+	BufferPercent float64
+	// BufferSteps are the buffer sizes keyed by the used slots in step mode
+	BufferSteps []BufferStep
+	// MinBufferSlotSize and MaxBufferSlotSize clamp the computed buffer size (zero is unlimited)
+	MinBufferSlotSize int64
+	MaxBufferSlotSize int64
+
+	// InterruptionTaints and InterruptionConditions are the taint keys and the node condition types signaling the node
+	// termination (e.g. the spot instance interruption), the interrupted nodes are counted as lost capacity
+	InterruptionTaints     []string
+	InterruptionConditions []string
+
+	// FallbackProvider is the node pool (e.g. on-demand) scaled up when the node pool is at the maximum size or failed to
+	// provide nodes in the last FallbackDuration, the nodes matching FallbackNodeSelector belong to it (nil disables)
+	FallbackProvider     nodepoolmanager.Provider
+	FallbackNodeSelector string
+	FallbackMaximumNode  int
+	FallbackDuration     time.Duration
+
+	// MaxNodeAge marks the nodes older than it as unschedulable, so they're deleted once their servers are finished and
+	// replaced by new nodes, a node is recycled at a time (zero disables)
+	MaxNodeAge time.Duration
+
+	// SessionStateKey is the dedicated server pod annotation (or label) holding the session state (idle, allocated or
+	// shutting-down), the idle servers are counted as available slots and don't prevent deleting their nodes (empty disables)
+	SessionStateKey string
+
+	// Agones enables reading the session states from the GameServer states instead of SessionStateKey, the Fleet replicas
+	// which are not created yet are deducted from the available slots (nil disables)
+	Agones *Agones
+
+	// ZoneBufferSlotSize is the minimum available slots of each zone (topology.kubernetes.io/zone label), the nodes are not
+	// marked as unschedulable below it and the zones are resized if the provider is zonal (zero disables)
+	ZoneBufferSlotSize int64
+
+	// MaxScaleUpStep is the maximum number of nodes added per scale up (zero is unlimited)
+	MaxScaleUpStep int
+	// MaxUnschedulePerPass is the maximum number of nodes marked as unschedulable per scale pass (zero is unlimited)
+	MaxUnschedulePerPass int
+	// MaxConcurrentDeletions is the maximum number of node deletions in flight (zero is unlimited)
+	MaxConcurrentDeletions int
+
+	// ScaleDownStrategy orders the nodes to mark as unschedulable on scale down (nil selects the fewest servers first)
+	ScaleDownStrategy ScaleDownStrategy
+
+	// ConsolidationScorer enables marking the lightly loaded nodes as unschedulable when their servers fit in the other nodes,
+	// the nodes are consolidated in the order of their scores (nil disables)
+	ConsolidationScorer NodeScorer
+
+	// ScaleUpCooldown prevents marking nodes as unschedulable for this duration after a scale up
+	ScaleUpCooldown time.Duration
+	// ScaleDownStabilization is the duration the extra capacity must persist before marking nodes as unschedulable
+	ScaleDownStabilization time.Duration
+	// MinResizeInterval is the minimum duration between two node pool resizes
+	MinResizeInterval time.Duration
+
+	// History stores the used slots every HistoryInterval, the Forecaster predicts the used slots ForecastHorizon ahead using it
+	// to grow the buffer before the demand (nil disables forecasting)
+	History         History
+	HistoryInterval time.Duration
+	Forecaster      Forecaster
+	ForecastHorizon time.Duration
+
+	// NodeBootTimeout replaces the nodes which are not ready (or not registered in the cluster) in this duration after creation,
+	// UnhealthyNodeTimeout replaces the empty nodes which are not ready for this duration (zero disables)
+	NodeBootTimeout      time.Duration
+	UnhealthyNodeTimeout time.Duration
+
+	// Reservations are added on top of the buffer size until they expire or are released (nil disables)
+	Reservations *Reservations
+
+	// Schedules override the buffer size and the minimum node pool size during their windows
+	Schedules []Schedule
 
 	ScaleLoopDuration time.Duration
 
 	Logger Logger
 }
 
+// Status is the state observed by the latest scale pass
+type Status struct {
+	Nodes          int
+	AvailableNodes int
+	// InterruptedNodes are the nodes signaled to terminate
+	InterruptedNodes int
+	AvailableSlot    int64
+	// AggregateAvailableSlot counts the slots in the total available resource, regardless of the fragmentation across the nodes
+	AggregateAvailableSlot int64
+	BufferSlotSize         int64
+	LastScaleTime          time.Time
+	LastError              error
+}
+
 type Scaler struct {
 	config *Config
 	npm    nodepoolmanager.Provider
 	k8s    Kubernetes
 	pw     *PodWatcher
 
-	stop chan bool
+	mu     sync.RWMutex
+	status Status
+
+	// lastScaleUp is the last time nodes were added or marked as schedulable
+	lastScaleUp time.Time
+	lastResize  time.Time
+	extraSince  time.Time
+	lastRecord  time.Time
+
+	// deleting holds the UIDs of the nodes requested to delete which are not removed from the cluster yet
+	deleting map[types.UID]bool
+	// draining holds the drain start of the nodes being drained keyed by UID
+	draining map[types.UID]time.Time
+	// notified holds the namespace/name of the dedicated server pods notified of their node draining
+	notified map[string]bool
+	// gameServers holds the GameServer states keyed by namespace/name in Agones mode
+	gameServers map[string]string
+	// fleetDeficit is the count of the Fleet replicas which are not created yet in Agones mode, they take the available slots
+	fleetDeficit int64
+
+	// primaryFailedAt is the last time the node pool failed to provide nodes
+	primaryFailedAt time.Time
+
+	// deletingUnregistered holds the names of the deleted node pool members which were never registered in the cluster
+	deletingUnregistered map[string]bool
+
+	reload chan *Config
+	stop   chan bool
 }
 
 func NewScaler(npm nodepoolmanager.Provider, k8s Kubernetes, config *Config) *Scaler {
@@ -51,18 +200,27 @@ func NewScaler(npm nodepoolmanager.Provider, k8s Kubernetes, config *Config) *Sc
 	if config.Logger == nil {
 		config.Logger = NewDefaultLogger(nil, nil, nil)
 	}
+	checkFallbackConfig(config)
 
-	// TODO: validate config
 	return &Scaler{
-		npm:    npm,
-		k8s:    k8s,
-		config: config,
-		stop:   make(chan bool, 1),
+		npm:                  npm,
+		k8s:                  k8s,
+		config:               config,
+		deleting:             make(map[types.UID]bool),
+		draining:             make(map[types.UID]time.Time),
+		notified:             make(map[string]bool),
+		deletingUnregistered: make(map[string]bool),
+		reload:               make(chan *Config, 1),
+		stop:                 make(chan bool, 1),
 	}
 }
 
 func (s *Scaler) Start() error {
-	var err error
+	err := validateConfig(s.config)
+	if err != nil {
+		return err
+	}
+
 	s.pw, err = s.k8s.NewPodWatcher(context.Background(), v1.NamespaceAll, fmt.Sprintf("%s=%s", s.config.PodLabelName, s.config.PodLabelValue))
 	if err != nil {
 		return err
@@ -76,13 +234,13 @@ func (s *Scaler) Start() error {
 			select {
 			case e := <-s.pw.Events:
 				if e.Type == watch.Added || e.Type == watch.Deleted {
-					if err = s.scale(); err != nil {
-						s.config.Logger.Errorf("error while trying to scale: ", err)
-					}
+					s.runScale()
 				}
 			case <-ticker.C:
-				if err = s.scale(); err != nil {
-					s.config.Logger.Errorf("error while trying to scale: ", err)
+				s.runScale()
+			case c := <-s.reload:
+				if err := s.applyConfig(c, ticker); err != nil {
+					s.config.Logger.Errorf("error while applying config: %s", err)
 				}
 			case <-s.stop:
 				return
@@ -98,58 +256,234 @@ func (s *Scaler) Stop() {
 	s.stop <- true
 }
 
+// UpdateConfig replaces the config of the running scaler, the new config is applied between two scale passes
+func (s *Scaler) UpdateConfig(config *Config) {
+	select {
+	case <-s.reload:
+	default:
+	}
+	s.reload <- config
+}
+
+// validateConfig returns an error if the scale loop can't run with the config
+func validateConfig(c *Config) error {
+	if c.ScaleLoopDuration <= 0 {
+		return fmt.Errorf("invalid scale loop duration %s", c.ScaleLoopDuration)
+	}
+
+	if c.PodLabelName == "" || c.PodLabelValue == "" {
+		return errors.New("pod label name and value are required")
+	}
+
+	if c.MaximumNode > 0 && c.MinimumNode > c.MaximumNode {
+		return fmt.Errorf("minimum node pool size %d exceeds the maximum node pool size %d", c.MinimumNode, c.MaximumNode)
+	}
+	return nil
+}
+
+// applyConfig replaces the config with the reloaded one, the current config is kept if the reloaded one is invalid
+func (s *Scaler) applyConfig(c *Config, ticker *time.Ticker) error {
+	if err := validateConfig(c); err != nil {
+		return fmt.Errorf("invalid config, keeping the current config: %w", err)
+	}
+
+	if c.Logger == nil {
+		c.Logger = s.config.Logger
+	}
+
+	if c.PodLabelName != s.config.PodLabelName || c.PodLabelValue != s.config.PodLabelValue {
+		pw, err := s.k8s.NewPodWatcher(context.Background(), v1.NamespaceAll, fmt.Sprintf("%s=%s", c.PodLabelName, c.PodLabelValue))
+		if err != nil {
+			return err
+		}
+		s.pw.Stop()
+		s.pw = pw
+	}
+
+	checkFallbackConfig(c)
+	if c.ScaleLoopDuration != s.config.ScaleLoopDuration {
+		ticker.Reset(c.ScaleLoopDuration)
+	}
+
+	s.config = c
+	s.config.Logger.Infof("config reloaded, buffer size: %d, minimum nodes: %d, maximum nodes: %d", c.BufferSlotSize, c.MinimumNode, c.MaximumNode)
+	return nil
+}
+
+// Status returns the state observed by the latest scale pass
+func (s *Scaler) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+func (s *Scaler) runScale() {
+	err := s.scale()
+	if nerr := s.notifyDrainingServers(); nerr != nil {
+		s.config.Logger.Errorf("error while notifying draining servers: %s", nerr)
+	}
+
+	s.mu.Lock()
+	s.status.LastScaleTime = time.Now()
+	s.status.LastError = err
+	s.mu.Unlock()
+
+	if err != nil {
+		s.config.Logger.Errorf("error while trying to scale: %s", err)
+	}
+}
+
+func (s *Scaler) observe(nodes *NodeList, availableSlot, aggregateSlot, bufferSize int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Nodes = len(nodes.Nodes)
+	s.status.AvailableNodes = len(nodes.AvailableNodes())
+	s.status.InterruptedNodes = len(nodes.InterruptedNodes())
+	s.status.AvailableSlot = availableSlot
+	s.status.AggregateAvailableSlot = aggregateSlot
+	s.status.BufferSlotSize = bufferSize
+}
+
 func (s *Scaler) scale() error {
 	s.config.Logger.Debugf("scaling")
-	nodes, err := s.k8s.Nodes(context.Background(), s.config.NodeSelector)
+	if err := s.refreshGameServers(); err != nil {
+		return err
+	}
+
+	nodes, err := s.nodes()
 	if err != nil {
 		return err
 	}
-	s.config.Logger.Debugf("current nodes: %d, available nodes: %d", len(nodes.Nodes), len(nodes.AvailableNodes()))
+	s.config.Logger.Debugf("current nodes: %d, available nodes: %d, interrupted nodes: %d", len(nodes.Nodes), len(nodes.AvailableNodes()), len(nodes.InterruptedNodes()))
+
+	if err = s.removeUnhealthyNodes(nodes); err != nil {
+		s.config.Logger.Errorf("error while removing unhealthy nodes: %s", err)
+	}
 
-	if len(nodes.Nodes) < s.config.MinimumNode {
-		s.config.Logger.Infof("current nodes are smaller than minimum size, resizing %d to %d", len(nodes.Nodes), s.config.MinimumNode)
-		return s.npm.ResizeNode(context.Background(), s.config.MinimumNode)
+	if err = s.warmUpNodes(nodes); err != nil {
+		s.config.Logger.Errorf("error while warming up nodes: %s", err)
 	}
 
-	availableSlot := nodes.AvailableSlot(Resource{
+	minimumNode := s.minimumNode()
+	if primary, _ := s.splitNodes(nodes); len(primary.Nodes) < minimumNode {
+		s.config.Logger.Infof("current nodes are smaller than minimum size, resizing %d to %d", len(primary.Nodes), minimumNode)
+		return s.resizeNode(minimumNode)
+	}
+
+	if err = s.recycleOldNodes(nodes); err != nil {
+		s.config.Logger.Errorf("error while recycling old nodes: %s", err)
+	}
+
+	if err = s.enforceSessionDeadline(nodes); err != nil {
+		s.config.Logger.Errorf("error while enforcing session deadline: %s", err)
+	}
+
+	availableSlot := s.availableSlot(nodes) - s.fleetDeficit
+	aggregateSlot := nodes.AggregateAvailableSlot(Resource{
 		Name:  v1.ResourceCPU,
 		Value: s.config.PodCPURequest,
 	})
-	s.config.Logger.Infof("available slot: %d, buffer size: %d", availableSlot, s.config.BufferSlotSize)
-	if availableSlot < s.config.BufferSlotSize {
-		if err = s.checkForScheduling(nodes, &Resource{
-			Name:  v1.ResourceCPU,
-			Value: s.config.PodCPURequest * s.config.BufferSlotSize,
-		}); err != nil && !errors.Is(err, ErrNotEnoughResources) {
-			return err
+	s.config.Logger.Debugf("available slot: %d, aggregate available slot: %d", availableSlot, aggregateSlot)
+	used := s.UsedSlot(nodes)
+	s.recordUsage(used)
+	bufferSize := s.bufferSize(used)
+	s.observe(nodes, availableSlot, aggregateSlot, bufferSize)
+	pendingSlot, err := s.pendingSlot()
+	if err != nil {
+		return err
+	}
+
+	provisioningSlot := s.provisioningSlot(nodes)
+	s.config.Logger.Infof("available slot: %d, provisioning slot: %d, pending slot: %d, buffer size: %d", availableSlot, provisioningSlot, pendingSlot, bufferSize)
+	if availableSlot <= bufferSize || pendingSlot > 0 {
+		s.extraSince = time.Time{}
+	}
+
+	scaleUp := neededSlot(availableSlot, provisioningSlot, pendingSlot, bufferSize) > 0
+	deficits := s.zoneDeficits(nodes)
+	zonal, isZonal := s.npm.(nodepoolmanager.ZonalProvider)
+	if len(deficits) > 0 && !isZonal {
+		s.config.Logger.Debugf("zones below the zone buffer: %v, the provider can't resize zones", deficits)
+	}
+
+	if scaleUp || (isZonal && len(deficits) > 0) {
+		if scaleUp {
+			if err = s.checkForScheduling(nodes, &Resource{
+				Name:  v1.ResourceCPU,
+				Value: s.config.PodCPURequest * (bufferSize + pendingSlot),
+			}); err != nil && !errors.Is(err, ErrNotEnoughResources) {
+				return err
+			}
 		}
 
-		nodes, err = s.k8s.Nodes(context.Background(), s.config.NodeSelector)
+		nodes, err = s.nodes()
 		if err != nil {
 			return err
 		}
 
-		availableSlot = nodes.AvailableSlot(Resource{
-			Name:  v1.ResourceCPU,
-			Value: s.config.PodCPURequest,
-		})
-		s.config.Logger.Infof("request to increase node pool size, available slot: %d, buffer size: %d", availableSlot, s.config.BufferSlotSize)
-		err = s.increaseNodePoolSize(nodes, &Resource{
+		availableSlot = s.availableSlot(nodes) - s.fleetDeficit
+		provisioningSlot = s.provisioningSlot(nodes)
+		needed := neededSlot(availableSlot, provisioningSlot, pendingSlot, bufferSize)
+
+		if deficits = s.zoneDeficits(nodes); isZonal && len(deficits) > 0 {
+			// the zones are resized for their deficits, and the node pool needed slots left are spread over the zones
+			var neededSlot int64
+			if scaleUp && needed > 0 {
+				neededSlot = needed
+			}
+			s.config.Logger.Infof("request to increase zones size, zone deficits: %v, needed slot: %d", deficits, neededSlot)
+			err = s.increaseZoneSize(zonal, nodes, deficits, neededSlot)
+		} else if scaleUp && needed > 0 {
+			s.config.Logger.Infof("request to increase node pool size, available slot: %d, provisioning slot: %d, pending slot: %d, buffer size: %d", availableSlot, provisioningSlot, pendingSlot, bufferSize)
+			err = s.increaseNodePoolSize(nodes, &Resource{
+				Name:  v1.ResourceCPU,
+				Value: s.config.PodCPURequest * needed,
+			})
+		}
+	}
+
+	if !scaleUp && availableSlot > bufferSize && s.isScaleDownStable() {
+		var marked int
+		marked, err = s.checkForUnscheduling(nodes, &Resource{
 			Name:  v1.ResourceCPU,
-			Value: s.config.PodCPURequest * (s.config.BufferSlotSize - availableSlot),
+			Value: s.config.PodCPURequest * (availableSlot - bufferSize),
 		})
-	} else if availableSlot > s.config.BufferSlotSize {
-		if err = s.checkForUnscheduling(nodes, &Resource{
-			Name:  v1.ResourceCPU,
-			Value: s.config.PodCPURequest * (availableSlot - s.config.BufferSlotSize),
-		}); err != nil {
+		if err != nil {
 			return err
 		}
+
+		// the consolidation shares the unscheduling limit of the pass
+		var limit int
+		if s.config.MaxUnschedulePerPass > 0 {
+			limit = s.config.MaxUnschedulePerPass - marked
+		}
+		if s.config.ConsolidationScorer != nil && (s.config.MaxUnschedulePerPass <= 0 || limit > 0) {
+			nodes, err = s.nodes()
+			if err != nil {
+				return err
+			}
+
+			if err = s.consolidate(nodes, bufferSize, limit); err != nil {
+				return err
+			}
+		}
 	}
 
 	return s.deleteExtraNodes()
 }
 
+func (s *Scaler) nodes() (*NodeList, error) {
+	nodes, err := s.k8s.Nodes(context.Background(), s.config.NodeSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes.SetDrainTaintKey(s.config.DrainTaintKey)
+	nodes.SetInterruptionSignals(s.config.InterruptionTaints, s.config.InterruptionConditions)
+	nodes.SetWarmUpTaintKey(s.config.WarmUpTaintKey)
+	return nodes, nil
+}
+
 func (s *Scaler) increaseNodePoolSize(nodes *NodeList, needs ...*Resource) error {
 	var maxNeededNodes int
 	for _, r := range needs {
@@ -160,16 +494,79 @@ func (s *Scaler) increaseNodePoolSize(nodes *NodeList, needs ...*Resource) error
 		}
 	}
 
-	size := maxNeededNodes + len(nodes.AvailableNodes())
-	s.config.Logger.Debugf("needed nodes: %d, current nodes: %d, size: %d", maxNeededNodes, len(nodes.AvailableNodes()), size)
+	primary, fallback := s.splitNodes(nodes)
+	// the schedulable nodes, available or not, and the interrupted nodes are still members of the node pool
+	var current int
+	for _, n := range primary.Nodes {
+		if n.IsSchedulable() || n.IsInterrupted() {
+			current++
+		}
+	}
+	size := maxNeededNodes + current
+	s.config.Logger.Debugf("needed nodes: %d, current nodes: %d, size: %d", maxNeededNodes, current, size)
+	if s.config.MaxScaleUpStep > 0 && size > len(primary.Nodes)+s.config.MaxScaleUpStep {
+		s.config.Logger.Infof("scale up to %d nodes limited to %d node(s) per step", size, s.config.MaxScaleUpStep)
+		size = len(primary.Nodes) + s.config.MaxScaleUpStep
+	}
+
+	var fallbackNeeded int
 	if size > s.config.MaximumNode {
+		fallbackNeeded = size - s.config.MaximumNode
 		size = s.config.MaximumNode
 	}
 
-	return s.npm.ResizeNode(context.Background(), size)
+	if !s.fallbackEnabled() {
+		return s.resizeNode(size)
+	}
+
+	if s.isFallingBack() {
+		return s.increaseFallbackSize(fallback, size-current+fallbackNeeded)
+	}
+
+	err := s.resizeNode(size)
+	if err != nil {
+		s.config.Logger.Errorf("error while resizing node pool: %s", err)
+		s.primaryFailed()
+		fallbackNeeded += size - current
+	}
+	return s.increaseFallbackSize(fallback, fallbackNeeded)
 }
 
-func (s *Scaler) checkForUnscheduling(nodes *NodeList, extra ...*Resource) error {
+func (s *Scaler) resizeNode(size int) error {
+	if s.config.MinResizeInterval > 0 && time.Since(s.lastResize) < s.config.MinResizeInterval {
+		s.config.Logger.Debugf("resize to %d skipped, last resize was %s ago", size, time.Since(s.lastResize))
+		return nil
+	}
+
+	err := s.npm.ResizeNode(context.Background(), size)
+	if err != nil {
+		return err
+	}
+	s.lastResize = time.Now()
+	s.lastScaleUp = s.lastResize
+	return nil
+}
+
+// isScaleDownStable reports whether the extra capacity persisted for the stabilization window and the scale up cooldown is passed
+func (s *Scaler) isScaleDownStable() bool {
+	now := time.Now()
+	if s.extraSince.IsZero() {
+		s.extraSince = now
+	}
+
+	if now.Sub(s.lastScaleUp) < s.config.ScaleUpCooldown {
+		s.config.Logger.Debugf("scale down skipped, in scale up cooldown")
+		return false
+	}
+
+	if now.Sub(s.extraSince) < s.config.ScaleDownStabilization {
+		s.config.Logger.Debugf("scale down skipped, extra capacity exists since %s", now.Sub(s.extraSince))
+		return false
+	}
+	return true
+}
+
+func (s *Scaler) checkForUnscheduling(nodes *NodeList, extra ...*Resource) (int, error) {
 	s.config.Logger.Debugf("check for unscheduling: %d nodes, extra %d %s", len(nodes.Nodes), extra[0].Value, extra[0].Name)
 	var minExtraNodes int64
 	for _, r := range extra {
@@ -186,25 +583,81 @@ func (s *Scaler) checkForUnscheduling(nodes *NodeList, extra ...*Resource) error
 
 	s.config.Logger.Debugf("%d extra node(s) exist", minExtraNodes)
 	if minExtraNodes <= 0 {
-		return nil
+		return 0, nil
+	}
+
+	if s.config.MaxUnschedulePerPass > 0 && minExtraNodes > int64(s.config.MaxUnschedulePerPass) {
+		s.config.Logger.Debugf("unscheduling limited to %d node(s) per pass", s.config.MaxUnschedulePerPass)
+		minExtraNodes = int64(s.config.MaxUnschedulePerPass)
 	}
 
-	SortNodesByPods(nodes.Nodes)
+	candidates := s.unschedulingCandidates(nodes.Nodes)
+	strategy := s.config.ScaleDownStrategy
+	if strategy == nil {
+		strategy = FewestServersStrategy{}
+	}
+	strategy.Sort(candidates, func(n *Node) []v1.Pod {
+		return s.activeServers(n.Pods)
+	})
+	// the fallback node pool nodes are scaled down first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return s.isFallbackNode(candidates[i]) && !s.isFallbackNode(candidates[j])
+	})
+
+	zoneSlots := nodes.ZoneAvailableSlot(Resource{
+		Name:  v1.ResourceCPU,
+		Value: s.config.PodCPURequest,
+	})
+
+	var marked int64
+	for _, n := range candidates {
+		if marked >= minExtraNodes {
+			break
+		}
+
+		if !s.keepsZoneBuffer(zoneSlots, n) {
+			continue
+		}
 
-	extraNodes := nodes.Nodes[0:minExtraNodes]
-	for _, n := range extraNodes {
 		err := s.markNodeAsUnschedulable(n)
 		if err != nil {
-			return err
+			return int(marked), err
 		}
 		s.config.Logger.Debugf("node %s marked as unschedulable with %d pod count", n.N.Name, len(n.Pods))
+		marked++
 	}
-	return nil
+	return int(marked), nil
+}
+
+// unschedulingCandidates filters out the unschedulable nodes and the nodes protected from scale down
+func (s *Scaler) unschedulingCandidates(nodes []*Node) []*Node {
+	now := time.Now()
+	var candidates []*Node
+	for _, n := range nodes {
+		if !n.IsSchedulable() || n.IsWarmingUp() {
+			continue
+		}
+
+		if n.IsScaleDownDisabled() || n.IsCordonDisabled() || n.IsProtected(now) {
+			s.config.Logger.Debugf("node %s is protected from unscheduling", n.N.Name)
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	return candidates
 }
 
 func (s *Scaler) checkForScheduling(n *NodeList, needs ...*Resource) error {
 	s.config.Logger.Debugf("check for scheduling: %d nodes, needs %d %s", len(n.Nodes), needs[0].Value, needs[0].Name)
-	nodes := n.UnschedulableNodes()
+	now := time.Now()
+	var nodes []*Node
+	for _, node := range n.UnschedulableNodes() {
+		// the old nodes are never scheduled again to be recycled
+		if s.isExpiredNode(node, now) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
 	if len(nodes) == 0 {
 		s.config.Logger.Debugf("no unscheduled node found")
 		return ErrNotEnoughResources
@@ -224,6 +677,7 @@ func (s *Scaler) checkForScheduling(n *NodeList, needs ...*Resource) error {
 		if err != nil {
 			return err
 		}
+		s.lastScaleUp = time.Now()
 		s.config.Logger.Debugf("node %s marked as schedulable", node.N.Name)
 
 		for r, v := range resources {
@@ -260,43 +714,169 @@ func (s *Scaler) markNodeAsUnschedulable(n *Node) error {
 
 func (s *Scaler) deleteExtraNodes() error {
 	s.config.Logger.Debugf("checking to delete extra nodes")
-	nodes, err := s.k8s.Nodes(context.Background(), s.config.NodeSelector)
+	nodes, err := s.nodes()
 	if err != nil {
 		return err
 	}
 
-	l := len(nodes.Nodes)
-	if l <= s.config.MinimumNode {
+	s.pruneDeleting(nodes)
+
+	// the minimum size applies to the node pool only, the fallback nodes are deleted regardless
+	minimumNode := s.minimumNode()
+	primary, fallback := s.splitNodes(nodes)
+	l := len(primary.Nodes)
+	for _, n := range primary.Nodes {
+		if s.deleting[n.N.UID] {
+			l--
+		}
+	}
+	if l <= minimumNode && len(fallback.Nodes) == 0 {
 		s.config.Logger.Debugf("already at minimum node pool size")
 		return nil
 	}
 
-	var deleteNodes []string
+	var deleteNodes []*Node
 	for _, node := range nodes.UnschedulableNodes() {
+		if s.deleting[node.N.UID] {
+			continue
+		}
+
+		isFallback := s.isFallbackNode(node)
+		if !isFallback && l <= minimumNode {
+			continue
+		}
+
+		if s.config.MaxConcurrentDeletions > 0 && s.inFlightDeletions()+len(deleteNodes) >= s.config.MaxConcurrentDeletions {
+			s.config.Logger.Debugf("deletion limited to %d node(s) in flight", s.config.MaxConcurrentDeletions)
+			break
+		}
+
+		if node.IsScaleDownDisabled() || node.IsProtected(time.Now()) {
+			s.config.Logger.Debugf("node %s is protected from deletion", node.N.Name)
+			continue
+		}
+
 		t, err := node.SchedulingMarkTimestamp()
 		if err != nil {
-			return err
+			s.config.Logger.Errorf("invalid scheduling mark timestamp on node %s: %s", node.N.Name, err)
+			continue
 		}
 
-		s.config.Logger.Debugf("checking node %s pods to delete, pods: %d, expired: %t", node.N.Name, len(s.filterPods(node.Pods)), time.Now().After(t.Add(s.config.EmptyNodeExpiration)))
-		if len(s.filterPods(node.Pods)) == 0 && time.Now().After(t.Add(s.config.EmptyNodeExpiration)) {
+		s.config.Logger.Debugf("checking node %s pods to delete, pods: %d, expired: %t", node.N.Name, len(s.activeServers(node.Pods)), time.Now().After(t.Add(s.config.EmptyNodeExpiration)))
+		if len(s.activeServers(node.Pods)) == 0 && time.Now().After(t.Add(s.config.EmptyNodeExpiration)) {
+			// the idle servers are shut down first, the node is deleted once they're terminated
+			if idle := s.filterPods(node.Pods); len(idle) > 0 {
+				if err := s.shutDownIdleServers(node); err != nil {
+					s.config.Logger.Errorf("error while shutting down idle servers of node %s: %s", node.N.Name, err)
+				}
+				continue
+			}
+
+			if s.config.DrainTimeout > 0 {
+				drained, err := s.drainNode(node, time.Now())
+				if err != nil {
+					s.config.Logger.Errorf("error while draining node %s: %s", node.N.Name, err)
+					continue
+				}
+				if !drained {
+					continue
+				}
+			}
+
 			s.config.Logger.Infof("node %s should delete", node.N.Name)
-			deleteNodes = append(deleteNodes, node.N.Name)
-			l--
-			if l <= s.config.MinimumNode {
-				break
+			deleteNodes = append(deleteNodes, node)
+			if !isFallback {
+				l--
 			}
 		}
 	}
-	return s.npm.DeleteNodes(context.Background(), deleteNodes)
+	if len(deleteNodes) == 0 {
+		return nil
+	}
+
+	err = s.deleteNodes(deleteNodes)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range deleteNodes {
+		s.deleting[node.N.UID] = true
+	}
+	return nil
+}
+
+// inFlightDeletions returns the number of the node deletions in flight, the unregistered node pool members included
+func (s *Scaler) inFlightDeletions() int {
+	return len(s.deleting) + len(s.deletingUnregistered)
+}
+
+// pruneDeleting forgets the deleted nodes which are removed from the cluster, and the drains of the nodes which are
+// removed or not marked as unschedulable anymore
+func (s *Scaler) pruneDeleting(nodes *NodeList) {
+	exists := make(map[types.UID]bool)
+	for _, n := range nodes.Nodes {
+		exists[n.N.UID] = true
+	}
+
+	for uid := range s.deleting {
+		if !exists[uid] {
+			delete(s.deleting, uid)
+		}
+	}
+
+	unschedulable := make(map[types.UID]bool)
+	for _, n := range nodes.UnschedulableNodes() {
+		unschedulable[n.N.UID] = true
+	}
+
+	for uid := range s.draining {
+		if !unschedulable[uid] {
+			delete(s.draining, uid)
+		}
+	}
 }
 
 func (s *Scaler) filterPods(pods []v1.Pod) []v1.Pod {
 	var filteredPods []v1.Pod
 	for _, p := range pods {
-		if v, ok := p.ObjectMeta.Labels[s.config.PodLabelName]; ok && v == s.config.PodLabelValue {
+		if s.isServerPod(p) {
 			filteredPods = append(filteredPods, p)
 		}
 	}
 	return filteredPods
 }
+
+// pendingSlot returns the count of the dedicated server pods which the scheduler failed to schedule
+func (s *Scaler) pendingSlot() (int64, error) {
+	pods, err := s.k8s.PendingPods(context.Background(), fmt.Sprintf("%s=%s", s.config.PodLabelName, s.config.PodLabelValue))
+	if err != nil {
+		return 0, err
+	}
+
+	var pending int64
+	for _, p := range pods.Items {
+		if isUnschedulablePod(p) {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+func isUnschedulablePod(p v1.Pod) bool {
+	if p.Status.Phase != v1.PodPending {
+		return false
+	}
+
+	for _, c := range p.Status.Conditions {
+		if c.Type == v1.PodScheduled && c.Status == v1.ConditionFalse && c.Reason == v1.PodReasonUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scaler) isServerPod(p v1.Pod) bool {
+	v, ok := p.ObjectMeta.Labels[s.config.PodLabelName]
+	return ok && v == s.config.PodLabelValue
+}
@@ -11,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/fake"
 	k8sT "k8s.io/client-go/testing"
 	"strings"
@@ -129,6 +130,70 @@ func TestScaler_scale(t *testing.T) {
 			},
 			unscheduled: map[string]bool{fmt.Sprintf(fmtNodeName, "2"): true},
 		},
+		{
+			name: "scale_down_unschedule_protected",
+			nodes: func() []v1.Node {
+				tmp := newNodeList(3, false).Items
+				tmp[2].ObjectMeta.Annotations[cordonDisabledAnnotation] = "true"
+				return tmp
+			}(),
+			expectedNodes: 3,
+			podsMap: map[string]*v1.PodList{
+				fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{
+					cpuResource:       "0.1",
+					isDedicatedServer: true,
+				})),
+				fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{
+					cpuResource:       "0.1",
+					isDedicatedServer: true,
+				})),
+				fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(1, requestPod{
+					cpuResource:       "0.1",
+					isDedicatedServer: true,
+				})),
+			},
+			unscheduled: map[string]bool{fmt.Sprintf(fmtNodeName, "1"): true},
+		},
+		{
+			name: "scale_down_foreign_cordon",
+			nodes: func() []v1.Node {
+				tmp := newNodeList(3, false).Items
+				tmp[2].Spec.Unschedulable = true // cordoned by another tool, without scaler annotations
+				return tmp
+			}(),
+			expectedNodes: 3,
+			podsMap: map[string]*v1.PodList{
+				fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{
+					cpuResource:       "0.1",
+					isDedicatedServer: true,
+				})),
+				fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{
+					cpuResource:       "0.1",
+					isDedicatedServer: true,
+				})),
+			},
+			unscheduled: map[string]bool{fmt.Sprintf(fmtNodeName, "2"): true},
+		},
+		{
+			name:          "scale_up_pending",
+			nodes:         newNodeList(3, false).Items,
+			expectedNodes: 4,
+			podsMap: map[string]*v1.PodList{
+				fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{
+					cpuResource:       "0.1",
+					isDedicatedServer: true,
+				})),
+				fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{
+					cpuResource:       "0.1",
+					isDedicatedServer: true,
+				})),
+				fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(1, requestPod{
+					cpuResource:       "0.1",
+					isDedicatedServer: true,
+				})),
+				"status.phase=Pending": newUnschedulablePodList(2),
+			},
+		},
 		{
 			name: "scale_up_schedule",
 			nodes: func() []v1.Node {
@@ -236,6 +301,221 @@ func TestScaler_scale(t *testing.T) {
 	}
 }
 
+func TestScaler_scaleDrainTaint(t *testing.T) {
+	nodes := newNodeList(4, false)
+	nodes.Items[3].Spec.Unschedulable = true // cordoned by another tool
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(1, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	npm := newNodePoolManagerMock(t, clientSet, nodes, false)
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:   nodeSelector,
+		MinimumNode:    2,
+		MaximumNode:    6,
+		PodCPURequest:  100,
+		BufferSlotSize: 4,
+		PodLabelName:   podLabelName,
+		PodLabelValue:  podLabelValue,
+		DrainTaintKey:  "kubescaler/draining",
+	})
+
+	err := srv.scale()
+	if err != nil {
+		t.Logf("expected scaler, got err: %s", err)
+		t.FailNow()
+	}
+
+	tmpNodes, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	if len(tmpNodes.Nodes) != 4 {
+		t.Logf("expected cordoned node to be kept, got %d nodes", len(tmpNodes.Nodes))
+		t.FailNow()
+	}
+
+	drained := tmpNodes.Nodes[2]
+	if !drained.IsMarkedAsUnschedulable() || drained.N.Spec.Unschedulable {
+		t.Logf("expected node %s to be tainted and not cordoned", drained.N.Name)
+		t.FailNow()
+	}
+
+	cordoned := tmpNodes.Nodes[3]
+	if cordoned.IsMarkedAsUnschedulable() || !cordoned.N.Spec.Unschedulable {
+		t.Logf("expected node %s to be left cordoned without taint", cordoned.N.Name)
+		t.FailNow()
+	}
+}
+
+func TestScaler_applyConfig(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	srv := NewScaler(nil, NewK8S(clientSet), &Config{
+		NodeSelector:      nodeSelector,
+		BufferSlotSize:    4,
+		PodLabelName:      podLabelName,
+		PodLabelValue:     podLabelValue,
+		ScaleLoopDuration: time.Second,
+	})
+	logger := srv.config.Logger
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	err := srv.applyConfig(&Config{
+		NodeSelector:      nodeSelector,
+		BufferSlotSize:    40,
+		PodLabelName:      podLabelName,
+		PodLabelValue:     podLabelValue,
+		ScaleLoopDuration: 2 * time.Second,
+	}, ticker)
+	if err != nil {
+		t.Logf("expected config to be applied, got err: %s", err)
+		t.FailNow()
+	}
+
+	if srv.config.BufferSlotSize != 40 || srv.config.ScaleLoopDuration != 2*time.Second {
+		t.Logf("expected reloaded config, got buffer size %d and loop duration %s", srv.config.BufferSlotSize, srv.config.ScaleLoopDuration)
+		t.FailNow()
+	}
+
+	if srv.config.Logger != logger {
+		t.Logf("expected logger to be kept after reload")
+		t.FailNow()
+	}
+
+	err = srv.applyConfig(&Config{
+		NodeSelector:      nodeSelector,
+		BufferSlotSize:    8,
+		PodLabelName:      podLabelName,
+		PodLabelValue:     podLabelValue,
+		ScaleLoopDuration: 0,
+	}, ticker)
+	if err == nil {
+		t.Logf("expected invalid config error, got nil")
+		t.FailNow()
+	}
+
+	if srv.config.BufferSlotSize != 40 || srv.config.ScaleLoopDuration != 2*time.Second {
+		t.Logf("expected current config to be kept, got buffer size %d and loop duration %s", srv.config.BufferSlotSize, srv.config.ScaleLoopDuration)
+		t.FailNow()
+	}
+}
+
+func TestScaler_scaleDownStabilization(t *testing.T) {
+	nodes := newNodeList(3, false)
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(1, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	npm := newNodePoolManagerMock(t, clientSet, nodes, false)
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:           nodeSelector,
+		MinimumNode:            2,
+		MaximumNode:            6,
+		PodCPURequest:          100,
+		BufferSlotSize:         4,
+		PodLabelName:           podLabelName,
+		PodLabelValue:          podLabelValue,
+		ScaleUpCooldown:        time.Minute,
+		ScaleDownStabilization: time.Minute,
+	})
+
+	tests := []struct {
+		name        string
+		lastScaleUp time.Time
+		extraSince  time.Time
+		unscheduled bool
+	}{
+		{
+			name: "extra_capacity_not_stable",
+		},
+		{
+			name:        "in_scale_up_cooldown",
+			lastScaleUp: time.Now().Add(-30 * time.Second),
+			extraSince:  time.Now().Add(-2 * time.Minute),
+		},
+		{
+			name:        "extra_capacity_stable",
+			extraSince:  time.Now().Add(-2 * time.Minute),
+			unscheduled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.lastScaleUp = tt.lastScaleUp
+			srv.extraSince = tt.extraSince
+
+			err := srv.scale()
+			if err != nil {
+				t.Logf("expected scaler, got err: %s", err)
+				t.FailNow()
+			}
+
+			if nodes.Items[2].Spec.Unschedulable != tt.unscheduled {
+				t.Logf("expected node unschedulable %t, got %t", tt.unscheduled, nodes.Items[2].Spec.Unschedulable)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestScaler_increaseNodePoolSize(t *testing.T) {
+	tests := []struct {
+		name         string
+		step         int
+		expectedSize int
+	}{
+		{
+			name:         "unlimited",
+			expectedSize: 6,
+		},
+		{
+			name:         "limited_step",
+			step:         1,
+			expectedSize: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newNodeList(2, false)
+			clientSet := newFakeCluster(nodes, nil)
+			npm := newNodePoolManagerMock(t, clientSet, nodes, false)
+			srv := NewScaler(npm, NewK8S(clientSet), &Config{
+				NodeSelector:   nodeSelector,
+				MaximumNode:    6,
+				MaxScaleUpStep: tt.step,
+			})
+
+			list, err := srv.nodes()
+			if err != nil {
+				t.Logf("expected node list, got err: %s", err)
+				t.FailNow()
+			}
+
+			err = srv.increaseNodePoolSize(list, &Resource{
+				Name:  v1.ResourceCPU,
+				Value: 10000,
+			})
+			if err != nil {
+				t.Logf("expected resize, got err: %s", err)
+				t.FailNow()
+			}
+
+			if len(nodes.Items) != tt.expectedSize {
+				t.Logf("expected %d nodes, got %d", tt.expectedSize, len(nodes.Items))
+				t.FailNow()
+			}
+		})
+	}
+}
+
 func waitForNode(nodes *v1.NodeList, count int) error {
 	t := time.NewTicker(500 * time.Millisecond)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -253,6 +533,32 @@ func waitForNode(nodes *v1.NodeList, count int) error {
 	}
 }
 
+// newFakeCluster returns a client set serving the given nodes and the pods by node field selector
+func newFakeCluster(nodes *v1.NodeList, podsMap map[string]*v1.PodList) *fake.Clientset {
+	clientSet := &fake.Clientset{}
+	clientSet.AddReactor("list", "nodes", func(a k8sT.Action) (bool, runtime.Object, error) {
+		return true, nodes, nil
+	})
+
+	clientSet.AddReactor("update", "nodes", func(a k8sT.Action) (bool, runtime.Object, error) {
+		n := a.(k8sT.UpdateAction).GetObject().(*v1.Node)
+		for i, node := range nodes.Items {
+			if node.Name == n.Name {
+				nodes.Items[i] = *n
+			}
+		}
+		return true, n, nil
+	})
+
+	clientSet.AddReactor("list", "pods", func(a k8sT.Action) (bool, runtime.Object, error) {
+		if list, ok := podsMap[a.(k8sT.ListAction).GetListRestrictions().Fields.String()]; ok {
+			return true, list, nil
+		}
+		return true, &v1.PodList{}, nil
+	})
+	return clientSet
+}
+
 func newNodeList(count int, randomNaming bool) *v1.NodeList {
 	list := &v1.NodeList{}
 
@@ -272,6 +578,7 @@ func newNodeList(count int, randomNaming bool) *v1.NodeList {
 			},
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      fmt.Sprintf("test-node-%s", name),
+				UID:       types.UID(uuid.New().String()),
 				Namespace: "",
 				Labels: map[string]string{
 					"role": "scalable",
@@ -335,6 +642,26 @@ func newPodList(pods []requestPod) *v1.PodList {
 	return list
 }
 
+func newUnschedulablePodList(count int) *v1.PodList {
+	list := newPodList(repeatRequestPod(count, requestPod{
+		cpuResource:       "0.1",
+		isDedicatedServer: true,
+	}))
+	for i := range list.Items {
+		list.Items[i].Status = v1.PodStatus{
+			Phase: v1.PodPending,
+			Conditions: []v1.PodCondition{
+				{
+					Type:   v1.PodScheduled,
+					Status: v1.ConditionFalse,
+					Reason: v1.PodReasonUnschedulable,
+				},
+			},
+		}
+	}
+	return list
+}
+
 func newNodePoolManagerMock(t *testing.T, k8s *fake.Clientset, nodes *v1.NodeList, randomNaming bool) nodepoolmanager.Provider {
 	ctrl := gomock.NewController(t)
 	npm := mocks.NewMockNodePoolProvider(ctrl)
@@ -0,0 +1,315 @@
+package kubescaler
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"time"
+)
+
+const (
+	ScalingPolicyGroup    = "kubescaler.theredrad.github.io"
+	ScalingPolicyVersion  = "v1alpha1"
+	ScalingPolicyKind     = "ScalingPolicy"
+	ScalingPolicyResource = "scalingpolicies"
+
+	defaultScaleLoopTickSeconds   = 10
+	defaultFallbackSeconds        = 600
+	defaultWarmUpSeconds          = 600
+	defaultSessionDeadlineSeconds = 300
+	defaultDrainNotifyPath        = "/drain"
+)
+
+var (
+	ScalingPolicyGVR = schema.GroupVersionResource{
+		Group:    ScalingPolicyGroup,
+		Version:  ScalingPolicyVersion,
+		Resource: ScalingPolicyResource,
+	}
+)
+
+// ScalingPolicy is the declarative form of the scaler Config, one Scaler is reconciled per policy object
+type ScalingPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScalingPolicySpec   `json:"spec"`
+	Status ScalingPolicyStatus `json:"status,omitempty"`
+}
+
+type ScalingPolicySpec struct {
+	NodeSelector string            `json:"nodeSelector"`
+	PodSelector  PodSelector       `json:"podSelector"`
+	Slot         SlotDefinition    `json:"slot"`
+	Provider     ProviderReference `json:"provider"`
+
+	MinimumNode int `json:"minimumNode"`
+	MaximumNode int `json:"maximumNode"`
+
+	BufferSlotSize    int64        `json:"bufferSlotSize"`
+	BufferMode        BufferMode   `json:"bufferMode,omitempty"`
+	BufferPercent     float64      `json:"bufferPercent,omitempty"`
+	BufferSteps       []BufferStep `json:"bufferSteps,omitempty"`
+	MinBufferSlotSize int64        `json:"minBufferSlotSize,omitempty"`
+	MaxBufferSlotSize int64        `json:"maxBufferSlotSize,omitempty"`
+
+	Schedules []ScheduleSpec `json:"schedules,omitempty"`
+
+	EmptyNodeExpirationSeconds int64 `json:"emptyNodeExpirationSeconds,omitempty"`
+	ScaleLoopTickSeconds       int64 `json:"scaleLoopTickSeconds,omitempty"`
+
+	ZoneBufferSlotSize int64 `json:"zoneBufferSlotSize,omitempty"`
+	MaxNodeAgeSeconds  int64 `json:"maxNodeAgeSeconds,omitempty"`
+
+	MaxScaleUpStep         int `json:"maxScaleUpStep,omitempty"`
+	MaxUnschedulePerPass   int `json:"maxUnschedulePerPass,omitempty"`
+	MaxConcurrentDeletions int `json:"maxConcurrentDeletions,omitempty"`
+
+	ScaleDownStrategy   string `json:"scaleDownStrategy,omitempty"`
+	ConsolidationScorer string `json:"consolidationScorer,omitempty"`
+
+	ScaleUpCooldownSeconds        int64 `json:"scaleUpCooldownSeconds,omitempty"`
+	ScaleDownStabilizationSeconds int64 `json:"scaleDownStabilizationSeconds,omitempty"`
+	MinResizeIntervalSeconds      int64 `json:"minResizeIntervalSeconds,omitempty"`
+
+	NodeBootTimeoutSeconds      int64 `json:"nodeBootTimeoutSeconds,omitempty"`
+	UnhealthyNodeTimeoutSeconds int64 `json:"unhealthyNodeTimeoutSeconds,omitempty"`
+
+	InterruptionTaints     []string      `json:"interruptionTaints,omitempty"`
+	InterruptionConditions []string      `json:"interruptionConditions,omitempty"`
+	Fallback               *FallbackSpec `json:"fallback,omitempty"`
+
+	Agones *AgonesSpec `json:"agones,omitempty"`
+
+	DrainTaintKey           string `json:"drainTaintKey,omitempty"`
+	DrainTimeoutSeconds     int64  `json:"drainTimeoutSeconds,omitempty"`
+	DrainGracePeriodSeconds int64  `json:"drainGracePeriodSeconds,omitempty"`
+
+	MaxDrainDurationSeconds           int64 `json:"maxDrainDurationSeconds,omitempty"`
+	SessionDeadlineGracePeriodSeconds int64 `json:"sessionDeadlineGracePeriodSeconds,omitempty"`
+
+	DrainNotification *DrainNotificationSpec `json:"drainNotification,omitempty"`
+
+	WarmUp *WarmUpSpec `json:"warmUp,omitempty"`
+}
+
+// FallbackSpec is a node pool of the policy provider scaled up when the provider node pool can't provide nodes
+type FallbackSpec struct {
+	NodePoolName    string `json:"nodePoolName"`
+	NodeSelector    string `json:"nodeSelector"`
+	MaximumNode     int    `json:"maximumNode"`
+	DurationSeconds int64  `json:"durationSeconds,omitempty"`
+}
+
+// DrainNotificationSpec signals the dedicated servers when their node is marked as unschedulable using the pod annotation
+// and/or the pod endpoint
+type DrainNotificationSpec struct {
+	Annotate bool   `json:"annotate,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// WarmUpSpec keeps the new nodes tainted until the pod matching the selector (e.g. the image pre-pull DaemonSet) is ready
+type WarmUpSpec struct {
+	TaintKey       string `json:"taintKey"`
+	PodSelector    string `json:"podSelector"`
+	TimeoutSeconds int64  `json:"timeoutSeconds,omitempty"`
+}
+
+// AgonesSpec enables the Agones mode for the GameServers of the namespace (empty for all namespaces)
+type AgonesSpec struct {
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type ScheduleSpec struct {
+	Name            string `json:"name"`
+	Cron            string `json:"cron"`
+	Timezone        string `json:"timezone,omitempty"`
+	DurationSeconds int64  `json:"durationSeconds"`
+	PreWarmSeconds  int64  `json:"preWarmSeconds,omitempty"`
+	BufferSlotSize  int64  `json:"bufferSlotSize,omitempty"`
+	MinimumNode     int    `json:"minimumNode,omitempty"`
+}
+
+type PodSelector struct {
+	LabelName  string `json:"labelName"`
+	LabelValue string `json:"labelValue"`
+
+	// SessionStateKey is the pod annotation (or label) holding the session state
+	SessionStateKey string `json:"sessionStateKey,omitempty"`
+}
+
+type SlotDefinition struct {
+	CPURequest int64 `json:"cpuRequest"`
+}
+
+// ProviderReference points to the node pool managed by the policy, the token is read from the referenced secret
+type ProviderReference struct {
+	Name           string           `json:"name"`
+	ClusterName    string           `json:"clusterName"`
+	NodePoolName   string           `json:"nodePoolName"`
+	TokenSecretRef *SecretReference `json:"tokenSecretRef,omitempty"`
+}
+
+func (r ProviderReference) equal(o ProviderReference) bool {
+	if r.Name != o.Name || r.ClusterName != o.ClusterName || r.NodePoolName != o.NodePoolName {
+		return false
+	}
+	if r.TokenSecretRef == nil || o.TokenSecretRef == nil {
+		return r.TokenSecretRef == o.TokenSecretRef
+	}
+	return *r.TokenSecretRef == *o.TokenSecretRef
+}
+
+type SecretReference struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+type ScalingPolicyStatus struct {
+	ObservedGeneration     int64        `json:"observedGeneration,omitempty"`
+	Nodes                  int          `json:"nodes"`
+	AvailableNodes         int          `json:"availableNodes"`
+	InterruptedNodes       int          `json:"interruptedNodes"`
+	AvailableSlot          int64        `json:"availableSlot"`
+	AggregateAvailableSlot int64        `json:"aggregateAvailableSlot"`
+	BufferSlotSize         int64        `json:"bufferSlotSize"`
+	LastScaleTime          *metav1.Time `json:"lastScaleTime,omitempty"`
+	LastError              string       `json:"lastError,omitempty"`
+}
+
+// Config converts the policy spec to the scaler config
+func (p *ScalingPolicy) Config(logger Logger) *Config {
+	tick := p.Spec.ScaleLoopTickSeconds
+	if tick <= 0 {
+		tick = defaultScaleLoopTickSeconds
+	}
+
+	var schedules []Schedule
+	for _, sc := range p.Spec.Schedules {
+		schedules = append(schedules, Schedule{
+			Name:           sc.Name,
+			Cron:           sc.Cron,
+			Timezone:       sc.Timezone,
+			Duration:       time.Duration(sc.DurationSeconds) * time.Second,
+			PreWarm:        time.Duration(sc.PreWarmSeconds) * time.Second,
+			BufferSlotSize: sc.BufferSlotSize,
+			MinimumNode:    sc.MinimumNode,
+		})
+	}
+
+	config := &Config{
+		NodeSelector:               p.Spec.NodeSelector,
+		MinimumNode:                p.Spec.MinimumNode,
+		MaximumNode:                p.Spec.MaximumNode,
+		PodCPURequest:              p.Spec.Slot.CPURequest,
+		PodLabelName:               p.Spec.PodSelector.LabelName,
+		PodLabelValue:              p.Spec.PodSelector.LabelValue,
+		SessionStateKey:            p.Spec.PodSelector.SessionStateKey,
+		EmptyNodeExpiration:        time.Duration(p.Spec.EmptyNodeExpirationSeconds) * time.Second,
+		BufferSlotSize:             p.Spec.BufferSlotSize,
+		BufferMode:                 p.Spec.BufferMode,
+		BufferPercent:              p.Spec.BufferPercent,
+		BufferSteps:                p.Spec.BufferSteps,
+		MinBufferSlotSize:          p.Spec.MinBufferSlotSize,
+		MaxBufferSlotSize:          p.Spec.MaxBufferSlotSize,
+		Schedules:                  schedules,
+		ScaleLoopDuration:          time.Duration(tick) * time.Second,
+		ZoneBufferSlotSize:         p.Spec.ZoneBufferSlotSize,
+		MaxNodeAge:                 time.Duration(p.Spec.MaxNodeAgeSeconds) * time.Second,
+		MaxScaleUpStep:             p.Spec.MaxScaleUpStep,
+		MaxUnschedulePerPass:       p.Spec.MaxUnschedulePerPass,
+		MaxConcurrentDeletions:     p.Spec.MaxConcurrentDeletions,
+		ScaleDownStrategy:          ScaleDownStrategies[p.Spec.ScaleDownStrategy],
+		ConsolidationScorer:        NodeScorers[p.Spec.ConsolidationScorer],
+		ScaleUpCooldown:            time.Duration(p.Spec.ScaleUpCooldownSeconds) * time.Second,
+		ScaleDownStabilization:     time.Duration(p.Spec.ScaleDownStabilizationSeconds) * time.Second,
+		MinResizeInterval:          time.Duration(p.Spec.MinResizeIntervalSeconds) * time.Second,
+		NodeBootTimeout:            time.Duration(p.Spec.NodeBootTimeoutSeconds) * time.Second,
+		UnhealthyNodeTimeout:       time.Duration(p.Spec.UnhealthyNodeTimeoutSeconds) * time.Second,
+		InterruptionTaints:         p.Spec.InterruptionTaints,
+		InterruptionConditions:     p.Spec.InterruptionConditions,
+		DrainTaintKey:              p.Spec.DrainTaintKey,
+		DrainTimeout:               time.Duration(p.Spec.DrainTimeoutSeconds) * time.Second,
+		DrainGracePeriod:           time.Duration(p.Spec.DrainGracePeriodSeconds) * time.Second,
+		MaxDrainDuration:           time.Duration(p.Spec.MaxDrainDurationSeconds) * time.Second,
+		SessionDeadlineGracePeriod: time.Duration(p.Spec.SessionDeadlineGracePeriodSeconds) * time.Second,
+		Logger:                     logger,
+	}
+
+	if p.Spec.MaxDrainDurationSeconds > 0 && p.Spec.SessionDeadlineGracePeriodSeconds <= 0 {
+		config.SessionDeadlineGracePeriod = defaultSessionDeadlineSeconds * time.Second
+	}
+
+	if n := p.Spec.DrainNotification; n != nil {
+		path := n.Path
+		if path == "" {
+			path = defaultDrainNotifyPath
+		}
+
+		config.NotifyDraining = n.Annotate
+		config.DrainNotifyPort = n.Port
+		config.DrainNotifyPath = path
+	}
+
+	if w := p.Spec.WarmUp; w != nil {
+		timeout := w.TimeoutSeconds
+		if timeout <= 0 {
+			timeout = defaultWarmUpSeconds
+		}
+
+		config.WarmUpTaintKey = w.TaintKey
+		config.WarmUpPodSelector = w.PodSelector
+		config.WarmUpTimeout = time.Duration(timeout) * time.Second
+	}
+
+	if f := p.Spec.Fallback; f != nil {
+		duration := f.DurationSeconds
+		if duration <= 0 {
+			duration = defaultFallbackSeconds
+		}
+
+		config.FallbackNodeSelector = f.NodeSelector
+		config.FallbackMaximumNode = f.MaximumNode
+		config.FallbackDuration = time.Duration(duration) * time.Second
+	}
+	return config
+}
+
+// fallbackPolicy returns the policy with the fallback node pool as provider, nil if the policy has no fallback
+func (p *ScalingPolicy) fallbackPolicy() *ScalingPolicy {
+	if p.Spec.Fallback == nil {
+		return nil
+	}
+
+	fp := *p
+	fp.Spec.Provider.NodePoolName = p.Spec.Fallback.NodePoolName
+	return &fp
+}
+
+func (p *ScalingPolicy) fallbackPool() string {
+	if p.Spec.Fallback == nil {
+		return ""
+	}
+	return p.Spec.Fallback.NodePoolName
+}
+
+func (s Status) policyStatus(generation int64) ScalingPolicyStatus {
+	ps := ScalingPolicyStatus{
+		ObservedGeneration:     generation,
+		Nodes:                  s.Nodes,
+		AvailableNodes:         s.AvailableNodes,
+		InterruptedNodes:       s.InterruptedNodes,
+		AvailableSlot:          s.AvailableSlot,
+		AggregateAvailableSlot: s.AggregateAvailableSlot,
+		BufferSlotSize:         s.BufferSlotSize,
+	}
+	if !s.LastScaleTime.IsZero() {
+		t := metav1.NewTime(s.LastScaleTime)
+		ps.LastScaleTime = &t
+	}
+	if s.LastError != nil {
+		ps.LastError = s.LastError.Error()
+	}
+	return ps
+}
@@ -0,0 +1,78 @@
+package kubescaler
+
+import (
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+// Schedule overrides the buffer size and the minimum node pool size during a recurring window
+type Schedule struct {
+	Name string `mapstructure:"name"`
+
+	// Cron is the window start in the standard cron format (ex: "0 18 * * 5" for Fridays at 18:00)
+	Cron     string `mapstructure:"cron"`
+	Timezone string `mapstructure:"timezone"`
+
+	Duration time.Duration `mapstructure:"duration"`
+	// PreWarm applies the overrides this duration before the window starts, so the nodes are ready on time
+	PreWarm time.Duration `mapstructure:"pre-warm"`
+
+	// BufferSlotSize raises the computed buffer size and MinimumNode overrides the config if not zero
+	BufferSlotSize int64 `mapstructure:"buffer-slot-size"`
+	MinimumNode    int   `mapstructure:"minimum-node"`
+}
+
+// IsActive reports whether the pre-warmed window contains the given time
+func (sc Schedule) IsActive(now time.Time) (bool, error) {
+	tz := sc.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+
+	schedule, err := cron.ParseStandard("CRON_TZ=" + tz + " " + sc.Cron)
+	if err != nil {
+		return false, err
+	}
+
+	start := schedule.Next(now.Add(-sc.Duration))
+	return !start.After(now.Add(sc.PreWarm)), nil
+}
+
+// scheduledOverrides returns the greatest buffer size and minimum node pool size of the active schedules
+func (s *Scaler) scheduledOverrides() (int64, int) {
+	var bufferSize int64
+	var minimumNode int
+	now := time.Now()
+	for _, sc := range s.config.Schedules {
+		active, err := sc.IsActive(now)
+		if err != nil {
+			s.config.Logger.Errorf("invalid schedule %s: %s", sc.Name, err)
+			continue
+		}
+		if !active {
+			continue
+		}
+
+		s.config.Logger.Debugf("schedule %s is active", sc.Name)
+		if sc.BufferSlotSize > bufferSize {
+			bufferSize = sc.BufferSlotSize
+		}
+		if sc.MinimumNode > minimumNode {
+			minimumNode = sc.MinimumNode
+		}
+	}
+	return bufferSize, minimumNode
+}
+
+// minimumNode returns the minimum node pool size, overridden by the active schedules
+func (s *Scaler) minimumNode() int {
+	_, minimumNode := s.scheduledOverrides()
+	if minimumNode == 0 {
+		return s.config.MinimumNode
+	}
+
+	if s.config.MaximumNode > 0 && minimumNode > s.config.MaximumNode {
+		return s.config.MaximumNode
+	}
+	return minimumNode
+}
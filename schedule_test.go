@@ -0,0 +1,74 @@
+package kubescaler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_IsActive(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Logf("expected location, got err: %s", err)
+		t.FailNow()
+	}
+
+	sc := Schedule{
+		Name:     "friday-evening",
+		Cron:     "0 18 * * 5",
+		Timezone: "Europe/Berlin",
+		Duration: 5 * time.Hour,
+		PreWarm:  15 * time.Minute,
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected bool
+	}{
+		{
+			name: "before_pre_warm",
+			now:  time.Date(2022, 2, 4, 17, 40, 0, 0, loc),
+		},
+		{
+			name:     "pre_warm",
+			now:      time.Date(2022, 2, 4, 17, 50, 0, 0, loc),
+			expected: true,
+		},
+		{
+			name:     "in_window_utc",
+			now:      time.Date(2022, 2, 4, 21, 59, 0, 0, time.UTC),
+			expected: true,
+		},
+		{
+			name: "window_ended",
+			now:  time.Date(2022, 2, 4, 23, 0, 0, 0, loc),
+		},
+		{
+			name: "other_day",
+			now:  time.Date(2022, 2, 3, 18, 30, 0, 0, loc),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, err := sc.IsActive(tt.now)
+			if err != nil {
+				t.Logf("expected schedule, got err: %s", err)
+				t.FailNow()
+			}
+
+			if active != tt.expected {
+				t.Logf("expected active %t, got %t", tt.expected, active)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestSchedule_IsActiveInvalid(t *testing.T) {
+	_, err := Schedule{Cron: "0 18 * *", Duration: time.Hour}.IsActive(time.Now())
+	if err == nil {
+		t.Logf("expected invalid cron error, got nil")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,104 @@
+package kubescaler
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// SessionStateIdle is a warm dedicated server waiting for a session, it's counted as an available slot
+	SessionStateIdle = "idle"
+	// SessionStateAllocated is a dedicated server running a session, its node is never deleted
+	SessionStateAllocated = "allocated"
+	// SessionStateShuttingDown is a dedicated server finishing its session
+	SessionStateShuttingDown = "shutting-down"
+)
+
+// sessionState returns the session state of the pod GameServer in Agones mode, otherwise the session state annotation (or label)
+func (s *Scaler) sessionState(p v1.Pod) string {
+	if s.gameServers != nil {
+		return s.gameServerSessionState(p.Namespace, p.Name)
+	}
+
+	v, ok := p.ObjectMeta.Annotations[s.config.SessionStateKey]
+	if !ok {
+		v = p.ObjectMeta.Labels[s.config.SessionStateKey]
+	}
+	return v
+}
+
+func (s *Scaler) isIdleServer(p v1.Pod) bool {
+	return s.isSessionAware() && s.isServerPod(p) && s.sessionState(p) == SessionStateIdle
+}
+
+func (s *Scaler) isSessionAware() bool {
+	return s.config.SessionStateKey != "" || s.gameServers != nil
+}
+
+// activeServers returns the dedicated server pods which are not idle, the servers without session state are active
+func (s *Scaler) activeServers(pods []v1.Pod) []v1.Pod {
+	var servers []v1.Pod
+	for _, p := range s.filterPods(pods) {
+		if !s.isIdleServer(p) {
+			servers = append(servers, p)
+		}
+	}
+	return servers
+}
+
+// shutDownIdleServers deletes the idle dedicated servers of the node to delete it in a later pass, the pods and the
+// session states are read again, so the servers allocated since the start of the pass keep the node
+func (s *Scaler) shutDownIdleServers(node *Node) error {
+	if err := s.refreshGameServers(); err != nil {
+		return err
+	}
+
+	pods, err := s.k8s.NodePods(context.Background(), node.N.Name)
+	if err != nil {
+		return err
+	}
+
+	if active := s.activeServers(pods.Items); len(active) > 0 {
+		s.config.Logger.Infof("node %s has %d active server(s), idle servers are not shut down", node.N.Name, len(active))
+		return nil
+	}
+
+	for _, p := range s.filterPods(pods.Items) {
+		if p.DeletionTimestamp != nil {
+			continue
+		}
+
+		s.config.Logger.Debugf("shutting down idle server %s/%s on node %s", p.Namespace, p.Name, node.N.Name)
+		err = s.k8s.DeletePod(context.Background(), &p)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// idleServers returns the count of the idle dedicated server pods on the available nodes
+func (s *Scaler) idleServers(nodes *NodeList) int64 {
+	if !s.isSessionAware() {
+		return 0
+	}
+
+	var idle int64
+	for _, n := range nodes.AvailableNodes() {
+		for _, p := range n.Pods {
+			if s.isIdleServer(p) {
+				idle++
+			}
+		}
+	}
+	return idle
+}
+
+// availableSlot returns the slots fitting on the available nodes plus the idle servers
+func (s *Scaler) availableSlot(nodes *NodeList) int64 {
+	return nodes.AvailableSlot(Resource{
+		Name:  v1.ResourceCPU,
+		Value: s.config.PodCPURequest,
+	}) + s.idleServers(nodes)
+}
@@ -0,0 +1,95 @@
+package kubescaler
+
+import (
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sT "k8s.io/client-go/testing"
+	"testing"
+)
+
+func TestScaler_sessionState(t *testing.T) {
+	const sessionStateKey = "kubescaler/session-state"
+
+	nodes := newNodeList(2, false)
+	node := &Node{N: &nodes.Items[1]}
+	if err := node.MarkAsUnschedulable(); err != nil {
+		t.Logf("expected marking node as unschedulable, got err: %s", err)
+		t.FailNow()
+	}
+
+	servers := newPodList(repeatRequestPod(6, requestPod{cpuResource: "0.1", isDedicatedServer: true}))
+	for i, state := range []string{SessionStateIdle, SessionStateIdle, SessionStateIdle, SessionStateAllocated, SessionStateShuttingDown, ""} {
+		if state != "" {
+			servers.Items[i].Annotations = map[string]string{sessionStateKey: state}
+		}
+	}
+	idleServers := newPodList(repeatRequestPod(2, requestPod{cpuResource: "0.1", isDedicatedServer: true}))
+	for i := range idleServers.Items {
+		idleServers.Items[i].Labels[sessionStateKey] = SessionStateIdle
+	}
+
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): servers,
+		fmt.Sprintf(fmtSpecNodeName, "1"): idleServers,
+	})
+	clientSet.AddReactor("delete", "pods", func(a k8sT.Action) (bool, runtime.Object, error) {
+		var remaining []v1.Pod
+		for _, p := range idleServers.Items {
+			if p.Name != a.(k8sT.DeleteAction).GetName() {
+				remaining = append(remaining, p)
+			}
+		}
+		idleServers.Items = remaining
+		return true, nil, nil
+	})
+
+	srv := NewScaler(newNodePoolManagerMock(t, clientSet, nodes, false), NewK8S(clientSet), &Config{
+		NodeSelector:    nodeSelector,
+		MinimumNode:     1,
+		PodCPURequest:   100,
+		PodLabelName:    podLabelName,
+		PodLabelValue:   podLabelValue,
+		SessionStateKey: sessionStateKey,
+	})
+
+	list, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	// the idle servers of the unschedulable node are not available
+	if available, used := srv.availableSlot(list), srv.UsedSlot(list); available != 7 || used != 3 {
+		t.Logf("expected 7 available and 3 used slots, got %d available and %d used slots", available, used)
+		t.FailNow()
+	}
+
+	// the idle servers allocated since the pods were read keep the node
+	idleServers.Items[0].Labels[sessionStateKey] = SessionStateAllocated
+	err = srv.shutDownIdleServers(list.Nodes[1])
+	if err != nil || len(idleServers.Items) != 2 {
+		t.Logf("expected the allocated server to keep the idle servers, got %d servers with err: %v", len(idleServers.Items), err)
+		t.FailNow()
+	}
+	idleServers.Items[0].Labels[sessionStateKey] = SessionStateIdle
+
+	// the idle servers are shut down first, then the node is deleted
+	for i, expectedNodes := range []int{2, 1} {
+		err = srv.deleteExtraNodes()
+		if err != nil {
+			t.Logf("expected deleting extra nodes, got err: %s", err)
+			t.FailNow()
+		}
+
+		if len(nodes.Items) != expectedNodes {
+			t.Logf("expected %d nodes after pass %d, got %d nodes", expectedNodes, i, len(nodes.Items))
+			t.FailNow()
+		}
+	}
+
+	if len(idleServers.Items) != 0 || len(nodes.Items) != 1 || nodes.Items[0].Name != fmt.Sprintf(fmtNodeName, "0") {
+		t.Logf("expected the node with only idle servers to be deleted, got %d nodes and %d idle servers", len(nodes.Items), len(idleServers.Items))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,134 @@
+package kubescaler
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"math"
+	"sort"
+	"time"
+)
+
+// sessionEndAnnotation is the RFC 3339 time a dedicated server pod session is expected to end
+const sessionEndAnnotation = "kubescaler/session-end"
+
+// ScaleDownStrategy orders the scale down candidates, the first nodes are marked as unschedulable first
+type ScaleDownStrategy interface {
+	Sort(nodes []*Node, servers func(n *Node) []v1.Pod)
+}
+
+// ScaleDownStrategies are the built-in scale down strategies by name
+var ScaleDownStrategies = map[string]ScaleDownStrategy{
+	"fewest-servers": FewestServersStrategy{},
+	"oldest":         OldestNodeStrategy{},
+	"newest":         NewestNodeStrategy{},
+	"session-time":   SessionTimeStrategy{},
+	"cost":           CostStrategy{},
+	"zone-balance":   ZoneBalanceStrategy{},
+}
+
+// FewestServersStrategy selects the nodes with the fewest dedicated server pods first, then the fewest pods
+type FewestServersStrategy struct{}
+
+func (FewestServersStrategy) Sort(nodes []*Node, servers func(n *Node) []v1.Pod) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		si, sj := len(servers(nodes[i])), len(servers(nodes[j]))
+		if si != sj {
+			return si < sj
+		}
+		return len(nodes[i].Pods) < len(nodes[j].Pods)
+	})
+}
+
+// OldestNodeStrategy selects the oldest nodes first
+type OldestNodeStrategy struct{}
+
+func (OldestNodeStrategy) Sort(nodes []*Node, servers func(n *Node) []v1.Pod) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].N.CreationTimestamp.Before(&nodes[j].N.CreationTimestamp)
+	})
+}
+
+// NewestNodeStrategy selects the newest nodes first
+type NewestNodeStrategy struct{}
+
+func (NewestNodeStrategy) Sort(nodes []*Node, servers func(n *Node) []v1.Pod) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[j].N.CreationTimestamp.Before(&nodes[i].N.CreationTimestamp)
+	})
+}
+
+// SessionTimeStrategy selects the nodes whose last session ends first, the servers without a valid session end annotation
+// are never expected to end
+type SessionTimeStrategy struct{}
+
+func (SessionTimeStrategy) Sort(nodes []*Node, servers func(n *Node) []v1.Pod) {
+	now := time.Now()
+	remaining := make(map[*Node]time.Duration)
+	for _, n := range nodes {
+		remaining[n] = sessionRemaining(servers(n), now)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return remaining[nodes[i]] < remaining[nodes[j]]
+	})
+}
+
+func sessionRemaining(servers []v1.Pod, now time.Time) time.Duration {
+	var remaining time.Duration
+	for _, p := range servers {
+		end, err := time.Parse(time.RFC3339, p.Annotations[sessionEndAnnotation])
+		if err != nil {
+			return math.MaxInt64
+		}
+
+		if r := end.Sub(now); r > remaining {
+			remaining = r
+		}
+	}
+	return remaining
+}
+
+// CostStrategy selects the most expensive nodes first by the cost annotation (or label), the nodes without cost are selected last
+type CostStrategy struct{}
+
+func (CostStrategy) Sort(nodes []*Node, servers func(n *Node) []v1.Pod) {
+	cost := make(map[*Node]float64)
+	for _, n := range nodes {
+		cost[n] = n.Cost()
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return cost[nodes[i]] > cost[nodes[j]]
+	})
+}
+
+// ZoneBalanceStrategy selects the nodes of the zone with the most candidates first to keep the zones balanced, the nodes
+// of a zone are ordered by the fewest servers strategy
+type ZoneBalanceStrategy struct{}
+
+func (ZoneBalanceStrategy) Sort(nodes []*Node, servers func(n *Node) []v1.Pod) {
+	zones := make(map[string][]*Node)
+	var names []string
+	for _, n := range nodes {
+		z := n.Zone()
+		if _, ok := zones[z]; !ok {
+			names = append(names, z)
+		}
+		zones[z] = append(zones[z], n)
+	}
+	sort.Strings(names)
+
+	for _, z := range names {
+		FewestServersStrategy{}.Sort(zones[z], servers)
+	}
+
+	for i := range nodes {
+		zone := names[0]
+		for _, z := range names[1:] {
+			if len(zones[z]) > len(zones[zone]) {
+				zone = z
+			}
+		}
+		nodes[i] = zones[zone][0]
+		zones[zone] = zones[zone][1:]
+	}
+}
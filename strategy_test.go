@@ -0,0 +1,99 @@
+package kubescaler
+
+import (
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestScaleDownStrategy_Sort(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		strategy ScaleDownStrategy
+		expected []int
+	}{
+		{
+			name:     "fewest_servers",
+			strategy: FewestServersStrategy{},
+			expected: []int{3, 2, 1, 0},
+		},
+		{
+			name:     "oldest",
+			strategy: OldestNodeStrategy{},
+			expected: []int{0, 1, 2, 3},
+		},
+		{
+			name:     "newest",
+			strategy: NewestNodeStrategy{},
+			expected: []int{3, 2, 1, 0},
+		},
+		{
+			name:     "session_time",
+			strategy: SessionTimeStrategy{},
+			expected: []int{3, 1, 0, 2},
+		},
+		{
+			name:     "cost",
+			strategy: CostStrategy{},
+			expected: []int{2, 0, 1, 3},
+		},
+		{
+			name:     "zone_balance",
+			strategy: ZoneBalanceStrategy{},
+			expected: []int{3, 1, 2, 0},
+		},
+	}
+
+	// node i is created i hours ago from the oldest and runs 3-i servers
+	newNodes := func() []*Node {
+		list := newNodeList(4, false)
+		var nodes []*Node
+		for i := range list.Items {
+			n := &Node{N: &list.Items[i]}
+			n.N.CreationTimestamp = metav1.NewTime(now.Add(time.Duration(i-4) * time.Hour))
+			n.Pods = newPodList(repeatRequestPod(3-i, requestPod{cpuResource: "0.1", isDedicatedServer: true})).Items
+			nodes = append(nodes, n)
+		}
+
+		for i, end := range []time.Duration{time.Hour, time.Minute, 0} {
+			for j := range nodes[i].Pods {
+				nodes[i].Pods[j].Annotations = map[string]string{sessionEndAnnotation: now.Add(end).Format(time.RFC3339)}
+			}
+		}
+		// a server without session end
+		nodes[2].Pods[0].Annotations = nil
+
+		nodes[0].N.Annotations[costAnnotation] = "0.5"
+		nodes[1].N.Labels[costAnnotation] = "0.2"
+		nodes[2].N.Annotations[costAnnotation] = "1.5"
+
+		nodes[0].N.Labels[zoneLabel] = "fra1"
+		nodes[1].N.Labels[zoneLabel] = "fra1"
+		nodes[2].N.Labels[zoneLabel] = "ams3"
+		nodes[3].N.Labels[zoneLabel] = "ams3"
+		return nodes
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newNodes()
+			tt.strategy.Sort(nodes, func(n *Node) []v1.Pod {
+				return n.Pods
+			})
+
+			var expected, got []string
+			for i, n := range nodes {
+				expected = append(expected, fmt.Sprintf(fmtNodeName, fmt.Sprint(tt.expected[i])))
+				got = append(got, n.N.Name)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(expected) {
+				t.Logf("expected order %v, got %v", expected, got)
+				t.FailNow()
+			}
+		})
+	}
+}
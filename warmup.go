@@ -0,0 +1,98 @@
+package kubescaler
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"time"
+)
+
+// defaultBootTimeout is the duration the not ready new nodes are counted as booting if NodeBootTimeout is not set
+const defaultBootTimeout = 10 * time.Minute
+
+// warmUpNodes removes the warm-up taint of the nodes whose pre-pull pod is ready, or which are warming up for longer
+// than WarmUpTimeout, so they're counted as available
+func (s *Scaler) warmUpNodes(nodes *NodeList) error {
+	if s.config.WarmUpTaintKey == "" {
+		return nil
+	}
+
+	selector := labels.Nothing()
+	if s.config.WarmUpPodSelector != "" {
+		var err error
+		selector, err = labels.Parse(s.config.WarmUpPodSelector)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, n := range nodes.Nodes {
+		if !n.IsWarmingUp() {
+			continue
+		}
+
+		warmingUp := now.Sub(n.N.CreationTimestamp.Time)
+		switch {
+		case hasReadyPod(n.Pods, selector):
+			s.config.Logger.Infof("node %s is warmed up in %s", n.N.Name, warmingUp.Round(time.Second))
+		case s.config.WarmUpTimeout > 0 && warmingUp > s.config.WarmUpTimeout:
+			s.config.Logger.Infof("node %s is not warmed up in %s, warm-up taint removed", n.N.Name, s.config.WarmUpTimeout)
+		default:
+			s.config.Logger.Debugf("node %s is warming up for %s", n.N.Name, warmingUp.Round(time.Second))
+			continue
+		}
+
+		n.removeWarmUpTaint()
+		err := s.k8s.UpdateNode(context.Background(), n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// provisioningSlot returns the slots of the nodes which are booting or warming up
+func (s *Scaler) provisioningSlot(nodes *NodeList) int64 {
+	var slot int64
+	for _, n := range nodes.ProvisioningNodes(time.Now(), s.bootTimeout()) {
+		slot += n.AvailableSlot(Resource{
+			Name:  v1.ResourceCPU,
+			Value: s.config.PodCPURequest,
+		})
+	}
+	return slot
+}
+
+// bootTimeout returns the duration the not ready new nodes are counted as booting
+func (s *Scaler) bootTimeout() time.Duration {
+	if s.config.NodeBootTimeout > 0 {
+		return s.config.NodeBootTimeout
+	}
+	return defaultBootTimeout
+}
+
+// neededSlot returns the slots to add to keep the buffer, the pending pods don't fit in the available slots, so they are
+// needed in addition to the buffer. The slots of the provisioning nodes are counted as they'll be available soon
+func neededSlot(availableSlot, provisioningSlot, pendingSlot, bufferSize int64) int64 {
+	needed := bufferSize - availableSlot
+	if needed < 0 {
+		needed = 0
+	}
+	return needed + pendingSlot - provisioningSlot
+}
+
+func hasReadyPod(pods []v1.Pod, selector labels.Selector) bool {
+	for _, p := range pods {
+		if !selector.Matches(labels.Set(p.ObjectMeta.Labels)) {
+			continue
+		}
+
+		for _, c := range p.Status.Conditions {
+			if c.Type == v1.PodReady && c.Status == v1.ConditionTrue {
+				return true
+			}
+		}
+	}
+	return false
+}
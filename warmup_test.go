@@ -0,0 +1,208 @@
+package kubescaler
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/theredrad/kubescaler/mocks"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestScaler_warmUpNodes(t *testing.T) {
+	now := time.Now()
+	nodes := newNodeList(4, false)
+	for i, age := range []time.Duration{time.Minute, time.Minute, time.Hour, time.Hour} {
+		nodes.Items[i].CreationTimestamp = metav1.NewTime(now.Add(-age))
+		if i < 3 {
+			nodes.Items[i].Spec.Taints = []v1.Taint{{Key: "kubescaler/warming-up", Value: "true", Effect: v1.TaintEffectNoSchedule}}
+		}
+	}
+
+	prePull := newPodList(repeatRequestPod(1, requestPod{cpuResource: "0.1"}))
+	prePull.Items[0].Labels["app"] = "image-prepull"
+	prePull.Items[0].Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): prePull,
+	})
+	srv := NewScaler(nil, NewK8S(clientSet), &Config{
+		NodeSelector:      nodeSelector,
+		PodCPURequest:     100,
+		PodLabelName:      podLabelName,
+		PodLabelValue:     podLabelValue,
+		WarmUpTaintKey:    "kubescaler/warming-up",
+		WarmUpPodSelector: "app=image-prepull",
+		WarmUpTimeout:     10 * time.Minute,
+	})
+
+	list, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	if available := len(list.AvailableNodes()); available != 1 {
+		t.Logf("expected the warming up nodes not to be available, got %d available nodes", available)
+		t.FailNow()
+	}
+
+	err = srv.warmUpNodes(list)
+	if err != nil {
+		t.Logf("expected warm up, got err: %s", err)
+		t.FailNow()
+	}
+
+	list, err = srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	// the node with the ready pre-pull pod and the timed out node are warmed up
+	expected := []bool{false, true, false, false}
+	for i, n := range list.Nodes {
+		if n.IsWarmingUp() != expected[i] {
+			t.Logf("expected node %s warming up to be %t", n.N.Name, expected[i])
+			t.FailNow()
+		}
+	}
+
+	if available := len(list.AvailableNodes()); available != 3 {
+		t.Logf("expected 3 available nodes, got %d", available)
+		t.FailNow()
+	}
+}
+
+func TestScaler_scaleWarmingNodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		bufferSize    int64
+		expectedSizes []int
+	}{
+		{
+			name:       "warming_nodes_cover_deficit",
+			bufferSize: 4,
+		},
+		{
+			name:          "warming_nodes_counted",
+			bufferSize:    8,
+			expectedSizes: []int{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newNodeList(3, false)
+			for i := 1; i < 3; i++ {
+				nodes.Items[i].Spec.Taints = []v1.Taint{{Key: "kubescaler/warming-up", Value: "true", Effect: v1.TaintEffectNoSchedule}}
+			}
+			clientSet := newFakeCluster(nodes, nil)
+
+			var sizes []int
+			npm := mocks.NewMockNodePoolProvider(gomock.NewController(t))
+			npm.EXPECT().ResizeNode(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, count int) error {
+				sizes = append(sizes, count)
+				return nil
+			}).AnyTimes()
+
+			srv := NewScaler(npm, NewK8S(clientSet), &Config{
+				NodeSelector:   nodeSelector,
+				MaximumNode:    10,
+				PodCPURequest:  500,
+				PodLabelName:   podLabelName,
+				PodLabelValue:  podLabelValue,
+				BufferSlotSize: tt.bufferSize,
+				WarmUpTaintKey: "kubescaler/warming-up",
+			})
+
+			err := srv.scale()
+			if err != nil {
+				t.Logf("expected scale, got err: %s", err)
+				t.FailNow()
+			}
+
+			if fmt.Sprint(sizes) != fmt.Sprint(tt.expectedSizes) {
+				t.Logf("expected resizes %v, got %v", tt.expectedSizes, sizes)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestScaler_scaleNotReadyNodes(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name          string
+		createdAt     time.Time
+		notReadySince time.Time
+		servers       int
+		expectedSizes []int
+	}{
+		{
+			name:          "booting_node_counted",
+			createdAt:     now.Add(-time.Minute),
+			notReadySince: now.Add(-time.Minute),
+		},
+		{
+			name:          "boot_timeout_exceeded",
+			createdAt:     now.Add(-time.Hour),
+			notReadySince: now.Add(-time.Hour),
+			expectedSizes: []int{3},
+		},
+		{
+			name:          "not_ready_after_ready",
+			createdAt:     now.Add(-5 * time.Minute),
+			notReadySince: now.Add(-2 * time.Minute),
+			expectedSizes: []int{3},
+		},
+		{
+			name:          "unhealthy_node_with_servers",
+			createdAt:     now.Add(-3 * time.Hour),
+			notReadySince: now.Add(-2 * time.Hour),
+			servers:       2,
+			expectedSizes: []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newNodeList(2, false)
+			nodes.Items[1].CreationTimestamp = metav1.NewTime(tt.createdAt)
+			nodes.Items[1].Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.NewTime(tt.notReadySince)}}
+			clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+				fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(10, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+				fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(tt.servers, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+			})
+
+			var sizes []int
+			npm := mocks.NewMockNodePoolProvider(gomock.NewController(t))
+			npm.EXPECT().ResizeNode(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, count int) error {
+				sizes = append(sizes, count)
+				return nil
+			}).AnyTimes()
+
+			srv := NewScaler(npm, NewK8S(clientSet), &Config{
+				NodeSelector:   nodeSelector,
+				MaximumNode:    10,
+				PodCPURequest:  100,
+				PodLabelName:   podLabelName,
+				PodLabelValue:  podLabelValue,
+				BufferSlotSize: 4,
+			})
+
+			err := srv.scale()
+			if err != nil {
+				t.Logf("expected scale, got err: %s", err)
+				t.FailNow()
+			}
+
+			if fmt.Sprint(sizes) != fmt.Sprint(tt.expectedSizes) {
+				t.Logf("expected resizes %v, got %v", tt.expectedSizes, sizes)
+				t.FailNow()
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package kubescaler
+
+import (
+	"context"
+	"github.com/theredrad/kubescaler/nodepoolmanager"
+	v1 "k8s.io/api/core/v1"
+	"math"
+	"sort"
+	"time"
+)
+
+// ZoneAvailableSlot returns the available slots per zone, the zones of all nodes are included even without available slot
+// and the nodes without zone label are skipped
+func (n *NodeList) ZoneAvailableSlot(need Resource) map[string]int64 {
+	slots := make(map[string]int64)
+	for _, node := range n.Nodes {
+		zone := node.Zone()
+		if zone == "" {
+			continue
+		}
+
+		if _, ok := slots[zone]; !ok {
+			slots[zone] = 0
+		}
+		if node.IsAvailable() {
+			slots[zone] += node.AvailableSlot(need)
+		}
+	}
+	return slots
+}
+
+// zoneDeficits returns the missing slots of the zones below the zone buffer, the slots of the booting and warming up nodes
+// are counted as they'll be available soon
+func (s *Scaler) zoneDeficits(nodes *NodeList) map[string]int64 {
+	deficits := make(map[string]int64)
+	if s.config.ZoneBufferSlotSize <= 0 {
+		return deficits
+	}
+
+	need := Resource{
+		Name:  v1.ResourceCPU,
+		Value: s.config.PodCPURequest,
+	}
+	slots := nodes.ZoneAvailableSlot(need)
+	for _, n := range nodes.ProvisioningNodes(time.Now(), s.bootTimeout()) {
+		if zone := n.Zone(); zone != "" {
+			slots[zone] += n.AvailableSlot(need)
+		}
+	}
+
+	for zone, slot := range slots {
+		if slot < s.config.ZoneBufferSlotSize {
+			deficits[zone] = s.config.ZoneBufferSlotSize - slot
+		}
+	}
+	return deficits
+}
+
+// keepsZoneBuffer reports whether the node zone keeps the zone buffer without the node slots, the slots are taken from the
+// zone slots if so
+func (s *Scaler) keepsZoneBuffer(zoneSlots map[string]int64, n *Node) bool {
+	zone := n.Zone()
+	if s.config.ZoneBufferSlotSize <= 0 || zone == "" {
+		return true
+	}
+
+	slot := n.AvailableSlot(Resource{
+		Name:  v1.ResourceCPU,
+		Value: s.config.PodCPURequest,
+	})
+	if zoneSlots[zone]-slot < s.config.ZoneBufferSlotSize {
+		s.config.Logger.Debugf("node %s is kept for zone %s buffer", n.N.Name, zone)
+		return false
+	}
+	zoneSlots[zone] -= slot
+	return true
+}
+
+// increaseZoneSize resizes the zones below the zone buffer to the needed nodes, and spreads the nodes of the node pool
+// needed slots left over the smallest zones, with the limits of the node pool resize. The nodes above the maximum node (or
+// failed to provide) are requested from the fallback node pool
+func (s *Scaler) increaseZoneSize(zonal nodepoolmanager.ZonalProvider, nodes *NodeList, deficits map[string]int64, neededSlot int64) error {
+	if s.config.MinResizeInterval > 0 && time.Since(s.lastResize) < s.config.MinResizeInterval {
+		s.config.Logger.Debugf("zones resize skipped, last resize was %s ago", time.Since(s.lastResize))
+		return nil
+	}
+
+	// the interrupted and the provisioning nodes are still members of the node pool
+	primary, fallback := s.splitNodes(nodes)
+	var size int
+	zoneSizes := make(map[string]int)
+	for _, n := range primary.Nodes {
+		if n.IsSchedulable() || n.IsInterrupted() {
+			size++
+			if zone := n.Zone(); zone != "" {
+				zoneSizes[zone]++
+			}
+		}
+	}
+
+	var zones []string
+	for zone := range deficits {
+		zones = append(zones, zone)
+		if _, ok := zoneSizes[zone]; !ok {
+			zoneSizes[zone] = 0
+		}
+	}
+	sort.Strings(zones)
+
+	capacity := nodes.Nodes[0].ResourceCapacity(v1.ResourceCPU) // TODO: using first cell as sample node
+	toNodes := func(slot int64) int {
+		return int(math.Ceil(float64(slot*s.config.PodCPURequest) / float64(capacity)))
+	}
+
+	if s.isFallingBack() {
+		var needed int
+		for _, zone := range zones {
+			needed += toNodes(deficits[zone])
+		}
+		if n := toNodes(neededSlot); n > needed {
+			needed = n
+		}
+		return s.increaseFallbackSize(fallback, needed)
+	}
+
+	added := make(map[string]int)
+	var total, fallbackNeeded int
+	add := func(zone string) bool {
+		if s.config.MaxScaleUpStep > 0 && total >= s.config.MaxScaleUpStep {
+			return false
+		}
+
+		if size+total >= s.config.MaximumNode {
+			fallbackNeeded++
+			return true
+		}
+		added[zone]++
+		total++
+		return true
+	}
+
+	var limited bool
+	for _, zone := range zones {
+		for i := 0; i < toNodes(deficits[zone]); i++ {
+			if !add(zone) {
+				limited = true
+				break
+			}
+		}
+	}
+
+	// the nodes added to the zones provide the node pool needed slots too, the rest is added to the smallest zones
+	var allZones []string
+	for zone := range zoneSizes {
+		allZones = append(allZones, zone)
+	}
+	sort.Strings(allZones)
+	for extra := toNodes(neededSlot) - total - fallbackNeeded; extra > 0 && !limited; extra-- {
+		smallest := allZones[0]
+		for _, zone := range allZones[1:] {
+			if zoneSizes[zone]+added[zone] < zoneSizes[smallest]+added[smallest] {
+				smallest = zone
+			}
+		}
+		limited = !add(smallest)
+	}
+	if limited {
+		s.config.Logger.Infof("zones scale up limited to %d node(s) per step", s.config.MaxScaleUpStep)
+	}
+
+	zones = zones[:0]
+	for zone := range added {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	for _, zone := range zones {
+		if s.isFallingBack() {
+			fallbackNeeded += added[zone]
+			continue
+		}
+
+		s.config.Logger.Infof("request to increase zone %s size to %d, zone needs %d slot(s)", zone, zoneSizes[zone]+added[zone], deficits[zone])
+		err := zonal.ResizeZone(context.Background(), zone, zoneSizes[zone]+added[zone])
+		if err != nil {
+			if !s.fallbackEnabled() {
+				return err
+			}
+
+			s.config.Logger.Errorf("error while resizing zone %s: %s", zone, err)
+			s.primaryFailed()
+			fallbackNeeded += added[zone]
+			continue
+		}
+		s.lastResize = time.Now()
+		s.lastScaleUp = s.lastResize
+	}
+
+	if !s.fallbackEnabled() {
+		if fallbackNeeded > 0 {
+			s.config.Logger.Infof("zones need %d node(s) more, the node pool is at the maximum size", fallbackNeeded)
+		}
+		return nil
+	}
+	return s.increaseFallbackSize(fallback, fallbackNeeded)
+}
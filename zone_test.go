@@ -0,0 +1,289 @@
+package kubescaler
+
+import (
+	"context"
+	"fmt"
+	"github.com/theredrad/kubescaler/nodepoolmanager"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+type zonalProviderMock struct {
+	nodepoolmanager.Provider
+	resized map[string]int
+}
+
+func (m *zonalProviderMock) ResizeZone(ctx context.Context, zone string, count int) error {
+	m.resized[zone] = count
+	return nil
+}
+
+func TestScaler_checkForUnschedulingZoneBuffer(t *testing.T) {
+	nodes := newNodeList(4, false)
+	for i, zone := range []string{"fra1", "fra1", "ams3", "ams3"} {
+		nodes.Items[i].Labels[zoneLabel] = zone
+	}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(1, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	srv := NewScaler(nil, NewK8S(clientSet), &Config{
+		NodeSelector:       nodeSelector,
+		PodCPURequest:      100,
+		PodLabelName:       podLabelName,
+		PodLabelValue:      podLabelValue,
+		ZoneBufferSlotSize: 10,
+	})
+
+	list, err := srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	_, err = srv.checkForUnscheduling(list, &Resource{
+		Name:  v1.ResourceCPU,
+		Value: 2000,
+	})
+	if err != nil {
+		t.Logf("expected unscheduling, got err: %s", err)
+		t.FailNow()
+	}
+
+	list, err = srv.nodes()
+	if err != nil {
+		t.Logf("expected node list, got err: %s", err)
+		t.FailNow()
+	}
+
+	unschedulable := list.UnschedulableNodes()
+	if len(unschedulable) != 1 || unschedulable[0].N.Name != fmt.Sprintf(fmtNodeName, "2") {
+		t.Logf("expected only %s to be unschedulable, got %d nodes", fmt.Sprintf(fmtNodeName, "2"), len(unschedulable))
+		t.FailNow()
+	}
+}
+
+func TestScaler_scaleZoneBuffer(t *testing.T) {
+	nodes := newNodeList(3, false)
+	for i, zone := range []string{"fra1", "fra1", "ams3"} {
+		nodes.Items[i].Labels[zoneLabel] = zone
+	}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(7, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(5, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	npm := &zonalProviderMock{
+		Provider: newNodePoolManagerMock(t, clientSet, nodes, false),
+		resized:  make(map[string]int),
+	}
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:       nodeSelector,
+		MinimumNode:        2,
+		MaximumNode:        6,
+		PodCPURequest:      100,
+		BufferSlotSize:     4,
+		PodLabelName:       podLabelName,
+		PodLabelValue:      podLabelValue,
+		ZoneBufferSlotSize: 6,
+	})
+
+	err := srv.scale()
+	if err != nil {
+		t.Logf("expected scale, got err: %s", err)
+		t.FailNow()
+	}
+
+	if len(npm.resized) != 2 || npm.resized["fra1"] != 3 || npm.resized["ams3"] != 2 {
+		t.Logf("expected fra1 resized to 3 and ams3 resized to 2, got %v", npm.resized)
+		t.FailNow()
+	}
+}
+
+func TestScaler_increaseZoneSizeLimits(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxScaleUpStep  int
+		lastResize      time.Duration
+		expectedResized map[string]int
+	}{
+		{
+			name:            "unlimited",
+			expectedResized: map[string]int{"ams3": 2, "fra1": 3},
+		},
+		{
+			name:            "limited_step",
+			maxScaleUpStep:  1,
+			expectedResized: map[string]int{"ams3": 2},
+		},
+		{
+			name:            "min_resize_interval",
+			lastResize:      time.Second,
+			expectedResized: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newNodeList(3, false)
+			for i, zone := range []string{"fra1", "fra1", "ams3"} {
+				nodes.Items[i].Labels[zoneLabel] = zone
+			}
+			clientSet := newFakeCluster(nodes, nil)
+			npm := &zonalProviderMock{
+				Provider: newNodePoolManagerMock(t, clientSet, nodes, false),
+				resized:  make(map[string]int),
+			}
+			srv := NewScaler(npm, NewK8S(clientSet), &Config{
+				NodeSelector:      nodeSelector,
+				MaximumNode:       6,
+				PodCPURequest:     100,
+				PodLabelName:      podLabelName,
+				PodLabelValue:     podLabelValue,
+				MaxScaleUpStep:    tt.maxScaleUpStep,
+				MinResizeInterval: time.Minute,
+			})
+			if tt.lastResize > 0 {
+				srv.lastResize = time.Now().Add(-tt.lastResize)
+			}
+
+			list, err := srv.nodes()
+			if err != nil {
+				t.Logf("expected node list, got err: %s", err)
+				t.FailNow()
+			}
+
+			err = srv.increaseZoneSize(npm, list, map[string]int64{"fra1": 5, "ams3": 5}, 0)
+			if err != nil {
+				t.Logf("expected zones resize, got err: %s", err)
+				t.FailNow()
+			}
+
+			if fmt.Sprint(npm.resized) != fmt.Sprint(tt.expectedResized) {
+				t.Logf("expected zones resized to %v, got %v", tt.expectedResized, npm.resized)
+				t.FailNow()
+			}
+		})
+	}
+}
+
+func TestScaler_scaleZoneDeficitAtMaximum(t *testing.T) {
+	nodes := newNodeList(4, false)
+	for i, zone := range []string{"fra1", "fra1", "fra1", "ams3"} {
+		nodes.Items[i].Labels[zoneLabel] = zone
+	}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(8, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "3"): newPodList(repeatRequestPod(9, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	npm := &zonalProviderMock{
+		Provider: newNodePoolManagerMock(t, clientSet, nodes, false),
+		resized:  make(map[string]int),
+	}
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:       nodeSelector,
+		MaximumNode:        4,
+		PodCPURequest:      100,
+		BufferSlotSize:     4,
+		PodLabelName:       podLabelName,
+		PodLabelValue:      podLabelValue,
+		ZoneBufferSlotSize: 2,
+		ScaleUpCooldown:    time.Hour,
+	})
+
+	err := srv.scale()
+	if err != nil {
+		t.Logf("expected scale, got err: %s", err)
+		t.FailNow()
+	}
+
+	// ams3 can't be resized at the maximum size, so the extra fra1 nodes are still scaled down
+	if len(npm.resized) != 0 || !srv.lastScaleUp.IsZero() {
+		t.Logf("expected no zone resize, got %v", npm.resized)
+		t.FailNow()
+	}
+
+	if len(nodes.Items) != 3 {
+		t.Logf("expected an extra node to be deleted, got %d nodes", len(nodes.Items))
+		t.FailNow()
+	}
+}
+
+func TestScaler_scaleZoneProvisioningNode(t *testing.T) {
+	nodes := newNodeList(3, false)
+	for i, zone := range []string{"fra1", "fra1", "ams3"} {
+		nodes.Items[i].Labels[zoneLabel] = zone
+	}
+	// the second fra1 node is booting
+	nodes.Items[1].CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+	nodes.Items[1].Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: nodes.Items[1].CreationTimestamp}}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(10, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(5, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	npm := &zonalProviderMock{
+		Provider: newNodePoolManagerMock(t, clientSet, nodes, false),
+		resized:  make(map[string]int),
+	}
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:       nodeSelector,
+		MinimumNode:        2,
+		MaximumNode:        6,
+		PodCPURequest:      100,
+		BufferSlotSize:     4,
+		PodLabelName:       podLabelName,
+		PodLabelValue:      podLabelValue,
+		ZoneBufferSlotSize: 4,
+	})
+
+	err := srv.scale()
+	if err != nil {
+		t.Logf("expected scale, got err: %s", err)
+		t.FailNow()
+	}
+
+	if len(npm.resized) != 0 {
+		t.Logf("expected no zone resize while the fra1 node is booting, got %v", npm.resized)
+		t.FailNow()
+	}
+}
+
+func TestScaler_scaleZoneDeficitAndPoolNeed(t *testing.T) {
+	nodes := newNodeList(3, false)
+	for i, zone := range []string{"fra1", "fra1", "ams3"} {
+		nodes.Items[i].Labels[zoneLabel] = zone
+	}
+	clientSet := newFakeCluster(nodes, map[string]*v1.PodList{
+		fmt.Sprintf(fmtSpecNodeName, "0"): newPodList(repeatRequestPod(10, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "1"): newPodList(repeatRequestPod(10, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+		fmt.Sprintf(fmtSpecNodeName, "2"): newPodList(repeatRequestPod(5, requestPod{cpuResource: "0.1", isDedicatedServer: true})),
+	})
+	npm := &zonalProviderMock{
+		Provider: newNodePoolManagerMock(t, clientSet, nodes, false),
+		resized:  make(map[string]int),
+	}
+	srv := NewScaler(npm, NewK8S(clientSet), &Config{
+		NodeSelector:       nodeSelector,
+		MaximumNode:        10,
+		PodCPURequest:      100,
+		BufferSlotSize:     25,
+		PodLabelName:       podLabelName,
+		PodLabelValue:      podLabelValue,
+		ZoneBufferSlotSize: 1,
+	})
+
+	err := srv.scale()
+	if err != nil {
+		t.Logf("expected scale, got err: %s", err)
+		t.FailNow()
+	}
+
+	// fra1 gets a node for its deficit, the second node needed by the node pool is added to the smallest zone
+	if len(npm.resized) != 2 || npm.resized["fra1"] != 3 || npm.resized["ams3"] != 2 {
+		t.Logf("expected fra1 resized to 3 and ams3 resized to 2, got %v", npm.resized)
+		t.FailNow()
+	}
+}